@@ -0,0 +1,130 @@
+package upstash
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/claywarren/upstash-go/internal/rest"
+)
+
+// autoPipeliner coalesces the commands sent concurrently within a window into a single pipeline request.
+type autoPipeliner struct {
+	client      rest.Client
+	window      time.Duration
+	maxCommands int
+
+	mu    sync.Mutex
+	queue []*queuedCommand
+	timer *time.Timer
+}
+
+// connectionStateCommands change the state of the connection they are sent on, such as its selected
+// database or authenticated user. They are never auto-pipelined, as they would apply to the commands
+// of other callers that follow them in the same batch.
+var connectionStateCommands = map[string]bool{
+	"SELECT":  true,
+	"AUTH":    true,
+	"HELLO":   true,
+	"WATCH":   true,
+	"UNWATCH": true,
+	"MULTI":   true,
+	"EXEC":    true,
+	"DISCARD": true,
+	"RESET":   true,
+	"CLIENT":  true,
+}
+
+// changesConnectionState reports whether the command in body changes the state of its connection.
+func changesConnectionState(body []any) bool {
+	return len(body) > 0 && connectionStateCommands[strings.ToUpper(fmt.Sprint(body[0]))]
+}
+
+// queuedCommand is a command waiting in the auto-pipeline queue for its reply.
+type queuedCommand struct {
+	body  []any
+	reply chan queuedReply
+}
+
+type queuedReply struct {
+	res any
+	err error
+}
+
+func newAutoPipeliner(client rest.Client, window time.Duration, maxCommands int) *autoPipeliner {
+	return &autoPipeliner{client: client, window: window, maxCommands: maxCommands}
+}
+
+// do queues the command body and waits for its reply. The queue is flushed once the window elapses
+// after its first command, or right away when it holds maxCommands commands.
+// If ctx is done first do returns ctx.Err(), but the command is still sent with the rest of its batch.
+func (p *autoPipeliner) do(ctx context.Context, body []any) (any, error) {
+	cmd := &queuedCommand{body: body, reply: make(chan queuedReply, 1)}
+
+	p.mu.Lock()
+	p.queue = append(p.queue, cmd)
+	if p.maxCommands > 0 && len(p.queue) >= p.maxCommands {
+		batch := p.take()
+		p.mu.Unlock()
+		go p.send(batch)
+	} else {
+		if p.timer == nil {
+			p.timer = time.AfterFunc(p.window, p.flush)
+		}
+		p.mu.Unlock()
+	}
+
+	select {
+	case r := <-cmd.reply:
+		return r.res, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// take empties the queue and stops its flush timer. It must be called with mu held.
+func (p *autoPipeliner) take() []*queuedCommand {
+	batch := p.queue
+	p.queue = nil
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	return batch
+}
+
+func (p *autoPipeliner) flush() {
+	p.mu.Lock()
+	batch := p.take()
+	p.mu.Unlock()
+	p.send(batch)
+}
+
+// send executes batch as a single pipeline and routes each result or error to the command it belongs to.
+func (p *autoPipeliner) send(batch []*queuedCommand) {
+	if len(batch) == 0 {
+		return
+	}
+	commands := make([][]any, len(batch))
+	for i, cmd := range batch {
+		commands[i] = cmd.body
+	}
+	res, err := p.client.Write(context.Background(), rest.Request{
+		Path: []string{"pipeline"},
+		Body: commands,
+	})
+	list, ok := res.([]any)
+	if err == nil && (!ok || len(list) != len(batch)) {
+		err = fmt.Errorf("unexpected auto-pipeline response: %T with %d results for %d commands", res, len(list), len(batch))
+	}
+	for i, cmd := range batch {
+		if err != nil {
+			cmd.reply <- queuedReply{err: err}
+			continue
+		}
+		r, err := pipelineResult(list[i])
+		cmd.reply <- queuedReply{res: r, err: err}
+	}
+}
@@ -2,17 +2,23 @@ package upstash
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/claywarren/upstash-go/internal/rest"
 )
 
-// RetryConfig defines the retry strategy for network errors.
+// RetryConfig defines the retry strategy for network errors and server (5xx) errors.
 type RetryConfig struct {
 	// Retries is the number of retry attempts. Defaults to 5.
+	// Server errors are only retried for read-only commands, or for every command with SendIdempotencyKey:
+	// a 5xx from a proxy may arrive after the server applied a write, and replaying it would apply it again.
 	Retries int
 	// Backoff is a function that returns the delay for a given retry attempt.
 	// Defaults to exponential backoff: exp(retryCount) * 50ms.
@@ -21,7 +27,14 @@ type RetryConfig struct {
 
 // Upstash is a client for the Upstash Redis REST API.
 type Upstash struct {
-	client rest.Client
+	client              rest.Client
+	subs                *subscriptionRegistry
+	maxCollectionResult int
+	maxBatchSize        int
+	batchSizes          map[string]int
+	username            string
+	health              *healthChecker
+	autoPipeline        *autoPipeliner
 }
 
 // Options provides configuration for the Upstash client.
@@ -35,8 +48,17 @@ type Options struct {
 	EdgeUrl string
 
 	// Token is the API token required for requests to the Upstash API.
+	// Falls back to `UPSTASH_REDIS_REST_TOKEN` environment variable.
 	Token string
 
+	// Username is the ACL user that Auth authenticates as when called without a username.
+	// This is useful for self-hosted deployments behind a REST shim; Upstash itself authenticates with Token.
+	Username string
+
+	// SkipCredentialsCheck disables the check in New that Url and Token are set.
+	// Use this if the client is constructed before its credentials are available.
+	SkipCredentialsCheck bool
+
 	// ReadFromEdge specifies if read requests should try to read from edge first.
 	ReadFromEdge bool
 
@@ -54,15 +76,80 @@ type Options struct {
 	// HTTPClient allows providing a custom http.Client.
 	HTTPClient *http.Client
 
-	// EnableAutoPipelining collects commands and sends them in a single batch.
+	// TLSConfig configures the transport of the default HTTP client, e.g. to require a minimum
+	// TLS version or to trust the CAs of a self-hosted proxy. It is ignored when HTTPClient is set.
+	TLSConfig *tls.Config
+
+	// EnableAutoPipelining collects the commands sent concurrently through Send, and the methods built on it,
+	// and sends them in a single pipeline request. Each caller still receives its own result or error.
+	// Blocking commands and commands that change connection state, such as SELECT or AUTH, are always sent on their own.
 	EnableAutoPipelining bool
 
 	// AutoPipelineWindow is the duration to wait before flushing the auto-pipeline queue.
 	// Defaults to 50ms.
 	AutoPipelineWindow time.Duration
 
+	// AutoPipelineMaxCommands caps the number of commands queued for auto-pipelining. It only applies
+	// with EnableAutoPipelining. When the cap is reached the queue is flushed immediately, regardless of
+	// AutoPipelineWindow, so a burst never holds more than this many commands in memory or sends them in
+	// a single pipeline request. Zero means no cap.
+	AutoPipelineMaxCommands int
+
+	// UseNumber decodes numbers in responses as json.Number instead of float64.
+	// This keeps the full precision of 64-bit integer replies beyond 2^53.
+	UseNumber bool
+
+	// HealthCheckInterval enables a background health check that PINGs the server every interval
+	// and caches the result for IsHealthy. It is opt-in and started by New; stop it with StopHealthCheck.
+	// Zero disables it.
+	HealthCheckInterval time.Duration
+
+	// MaxBatchSize caps the number of elements sent in a single bulk command such as MGET, SADD or a
+	// multi-member ZADD. Larger bulk commands are split into chunks sent together in one pipeline
+	// request; unlike a single command, the chunks are not applied atomically. Zero means no cap.
+	MaxBatchSize int
+
+	// BatchSizes overrides MaxBatchSize per command, keyed by case-insensitive command name,
+	// e.g. {"ZADD": 500} to send smaller chunks for the wider ZADD arguments.
+	// An entry takes precedence over MaxBatchSize; a zero or negative entry is ignored.
+	BatchSizes map[string]int
+
+	// MaxCollectionResult caps the number of elements a collection command may request,
+	// e.g. the count of SPOP and SRANDMEMBER or the width of an LRANGE or ZRANGE.
+	// Requests over the cap fail with ErrCollectionTooLarge before they are sent. Ranges whose width
+	// depends on the data, such as 0 to -1 or a score range without a limit, are first measured with
+	// LLEN, ZCARD, ZCOUNT or ZLEXCOUNT, costing one extra request; replies are checked too. Zero means no cap.
+	MaxCollectionResult int
+
 	// LatencyLogger is a callback function to log request latency.
 	LatencyLogger func(command string, latency time.Duration)
+
+	// RequestLogger is a callback function to inspect each request right before it is sent,
+	// with its HTTP method, URL and exact JSON body. Headers, including the Authorization token, are not passed.
+	RequestLogger func(method, url string, body []byte)
+
+	// RequestTimeout bounds the duration of each request, including retries.
+	// It does not apply to blocking commands. Zero means no timeout.
+	RequestTimeout time.Duration
+
+	// BlockingRequestTimeout bounds the duration of each request containing a blocking command
+	// (BLPOP, BRPOP, BLMPOP, BZPOPMIN, BZPOPMAX, XREAD and XREADGROUP with BLOCK, ...).
+	// It should exceed the server-side timeout passed to the command. Zero means no timeout.
+	BlockingRequestTimeout time.Duration
+
+	// SendIdempotencyKey attaches a unique Idempotency-Key header to each request.
+	// The key stays the same across retries of a request, so an idempotency-aware proxy can dedupe replayed writes.
+	SendIdempotencyKey bool
+
+	// ArgEncoder renders each command argument that is not a string into the token sent to the server,
+	// e.g. to format time.Time values or domain types consistently. It is called for numbers too,
+	// so it should fall back to fmt.Sprint for the types it does not handle.
+	// By default arguments are sent as their JSON encoding.
+	ArgEncoder func(any) (string, error)
+
+	// ResponseInspector is a callback function to inspect the headers of each response, successful or not,
+	// e.g. to record the region or cache status reported by Upstash.
+	ResponseInspector func(header http.Header)
 }
 
 // New creates a new Upstash client with the provided options.
@@ -78,6 +165,15 @@ func New(options Options) (Upstash, error) {
 		options.Token = os.Getenv("UPSTASH_REDIS_REST_TOKEN")
 	}
 
+	if !options.SkipCredentialsCheck {
+		if options.Url == "" {
+			return Upstash{}, fmt.Errorf("missing url: set Options.Url or UPSTASH_REDIS_REST_URL")
+		}
+		if options.Token == "" {
+			return Upstash{}, fmt.Errorf("missing token: set Options.Token or UPSTASH_REDIS_REST_TOKEN")
+		}
+	}
+
 	if !options.DisableTelemetry {
 		if os.Getenv("UPSTASH_DISABLE_TELEMETRY") != "" {
 			options.DisableTelemetry = true
@@ -92,14 +188,32 @@ func New(options Options) (Upstash, error) {
 		options.Retry.Backoff = rest.DefaultBackoff
 	}
 	if options.HTTPClient == nil {
-		options.HTTPClient = &http.Client{}
+		options.HTTPClient = newHTTPClient(options.TLSConfig)
 	}
 	if options.AutoPipelineWindow == 0 {
 		options.AutoPipelineWindow = 50 * time.Millisecond
 	}
 
+	batchSizes := make(map[string]int, len(options.BatchSizes))
+	for command, size := range options.BatchSizes {
+		if size > 0 {
+			batchSizes[strings.ToUpper(command)] = size
+		}
+	}
+
 	u := Upstash{
-		client: rest.New(options.Url, options.EdgeUrl, options.Token, options.EnableBase64, options.DisableTelemetry, options.Retry.Retries, options.Retry.Backoff, options.HTTPClient, options.LatencyLogger),
+		subs:                &subscriptionRegistry{},
+		maxCollectionResult: options.MaxCollectionResult,
+		maxBatchSize:        options.MaxBatchSize,
+		batchSizes:          batchSizes,
+		username:            options.Username,
+		client:              rest.New(options.Url, options.EdgeUrl, options.Token, options.EnableBase64, options.DisableTelemetry, options.Retry.Retries, options.Retry.Backoff, options.HTTPClient, options.LatencyLogger, options.RequestTimeout, options.BlockingRequestTimeout, options.SendIdempotencyKey, options.UseNumber, options.RequestLogger, options.ArgEncoder, options.ResponseInspector),
+	}
+	if options.EnableAutoPipelining {
+		u.autoPipeline = newAutoPipeliner(u.client, options.AutoPipelineWindow, options.AutoPipelineMaxCommands)
+	}
+	if options.HealthCheckInterval > 0 {
+		u.startHealthCheck(options.HealthCheckInterval)
 	}
 
 	return u, nil
@@ -114,12 +228,89 @@ func (u *Upstash) Send(ctx context.Context, command string, args ...any) (any, e
 	body = append(body, command)
 	body = append(body, args...)
 
+	if u.autoPipeline != nil && !rest.IsBlocking(body) && !changesConnectionState(body) {
+		return u.autoPipeline.do(ctx, body)
+	}
 	res, err := u.client.Write(ctx, rest.Request{
 		Body: body,
 	})
 	return res, err
 }
 
+// newHTTPClient returns the default HTTP client, with a transport using tlsConfig if it is not nil.
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	if tlsConfig == nil {
+		return &http.Client{}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig.Clone()
+	return &http.Client{Transport: transport}
+}
+
+// batchSize returns the maximum number of elements sent in a single command, or zero for no cap.
+func (u *Upstash) batchSize(command string) int {
+	if size, ok := u.batchSizes[command]; ok {
+		return size
+	}
+	return u.maxBatchSize
+}
+
+// sendBatched sends command with the leading arguments lead followed by the arguments of each item.
+// If there are more items than the command's batch size, they are split into chunks that are sent as a pipeline.
+// It returns the result of each chunk.
+func (u *Upstash) sendBatched(ctx context.Context, command string, lead []any, items [][]any) ([]any, error) {
+	size := u.batchSize(command)
+	if size <= 0 || len(items) <= size {
+		size = max(len(items), 1)
+	}
+	chunks := make([][]any, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items) || start == 0; start += size {
+		args := append([]any{}, lead...)
+		for _, item := range items[start:min(start+size, len(items))] {
+			args = append(args, item...)
+		}
+		chunks = append(chunks, args)
+	}
+	if len(chunks) == 1 {
+		res, err := u.Send(ctx, command, chunks[0]...)
+		if err != nil {
+			return nil, err
+		}
+		return []any{res}, nil
+	}
+
+	pipe := u.Pipeline()
+	for _, args := range chunks {
+		pipe.Push(command, args...)
+	}
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != len(chunks) {
+		return nil, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	results := make([]any, len(res))
+	for i, r := range res {
+		results[i], err = pipelineResult(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// SendRaw executes a command given as a complete argv array, e.g. []any{"HSET", "key", "field", "value"}.
+// The array is sent as-is, which is useful for proxying commands that are already tokenized.
+func (u *Upstash) SendRaw(ctx context.Context, argv []any) (any, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return u.client.Write(ctx, rest.Request{
+		Body: argv,
+	})
+}
+
 // Pipeline represents a sequence of commands to be executed via Upstash pipeline.
 type Pipeline struct {
 	commands [][]any
@@ -142,6 +333,11 @@ func (p *Pipeline) Push(command string, args ...any) {
 	p.commands = append(p.commands, cmd)
 }
 
+// Select queues a SELECT, so that the commands pushed after it run against the database with the given index.
+func (p *Pipeline) Select(db int) {
+	p.Push("SELECT", db)
+}
+
 // Exec executes the queued commands in the pipeline.
 // Returns an array of results corresponding to the commands.
 func (p *Pipeline) Exec(ctx context.Context) ([]any, error) {
@@ -167,10 +363,42 @@ func (p *Pipeline) Exec(ctx context.Context) ([]any, error) {
 	return nil, fmt.Errorf("unexpected return type for pipeline: %T", res)
 }
 
+// ExecWithTimeout is like Exec, but bounds the whole batch by timeout.
+// Blocking commands would consume the budget shared with the other commands, so it returns an error
+// without sending anything if the pipeline mixes blocking and non-blocking commands.
+func (p *Pipeline) ExecWithTimeout(ctx context.Context, timeout time.Duration) ([]any, error) {
+	blocking := 0
+	for _, cmd := range p.commands {
+		if rest.IsBlocking(cmd) {
+			blocking++
+		}
+	}
+	if blocking > 0 && blocking < len(p.commands) {
+		return nil, fmt.Errorf("pipeline mixes blocking and non-blocking commands")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return p.Exec(ctx)
+}
+
+// pipelineResult extracts the result of a single command from a pipeline or transaction response.
+func pipelineResult(item any) (any, error) {
+	m, ok := item.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected return type for pipeline command: %T", item)
+	}
+	if errStr, ok := m["error"].(string); ok && errStr != "" {
+		return nil, rest.CommandError(errStr)
+	}
+	return m["result"], nil
+}
+
 // Multi represents a sequence of commands to be executed as a transaction.
 type Multi struct {
 	commands [][]any
 	client   rest.Client
+	// err is the first validation error recorded by Push, reported by Exec.
+	err error
 }
 
 // Multi creates a new Multi (Transaction).
@@ -181,6 +409,19 @@ func (u *Upstash) Multi() *Multi {
 	}
 }
 
+// validateCommand checks the command queued at index for obvious mistakes.
+func validateCommand(index int, command string, args []any) error {
+	if command == "" {
+		return fmt.Errorf("command %d: empty command name", index)
+	}
+	for i, a := range args {
+		if a == nil {
+			return fmt.Errorf("command %d (%s): nil argument at position %d", index, command, i)
+		}
+	}
+	return nil
+}
+
 // Tx creates a new Multi (Transaction). Alias for Multi().
 func (u *Upstash) Tx() *Multi {
 	return u.Multi()
@@ -196,7 +437,7 @@ func (u *Upstash) Watch(ctx context.Context, keys ...string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("WATCH", res)
 }
 
 // Unwatch flushes all the previously watched keys for a transaction.
@@ -205,26 +446,39 @@ func (u *Upstash) Unwatch(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("UNWATCH", res)
 }
 
 // Discard flushes all previously queued commands in a transaction.
 // Note: In REST API, this is usually client-side, but added for parity.
 func (m *Multi) Discard() {
 	m.commands = make([][]any, 0)
+	m.err = nil
 }
 
 // Push adds a command to the transaction.
+// A command with an empty name or a nil argument is recorded as an error that Exec reports before sending anything.
 func (m *Multi) Push(command string, args ...any) {
+	if m.err == nil {
+		m.err = validateCommand(len(m.commands), command, args)
+	}
 	cmd := make([]any, 0, 1+len(args))
 	cmd = append(cmd, command)
 	cmd = append(cmd, args...)
 	m.commands = append(m.commands, cmd)
 }
 
+// Select queues a SELECT, so that the commands pushed after it run against the database with the given index.
+func (m *Multi) Select(db int) {
+	m.Push("SELECT", db)
+}
+
 // Exec executes the queued commands in the transaction.
 // Returns an array of results corresponding to the commands.
 func (m *Multi) Exec(ctx context.Context) ([]any, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
 	if len(m.commands) == 0 {
 		return []any{}, nil
 	}
@@ -268,9 +522,15 @@ func (u *Upstash) scan(ctx context.Context, key, cursor string, options ScanOpti
 		return ScanResult{}, err
 	}
 
-	list := res.([]any)
+	list, err := asList(command, res)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if len(list) != 2 {
+		return ScanResult{}, unexpectedType(command, res)
+	}
 	cursorOut := fmt.Sprint(list[0])
-	itemsRaw := list[1].([]any)
+	itemsRaw, _ := list[1].([]any)
 	items := make([]string, len(itemsRaw))
 	for i, v := range itemsRaw {
 		items[i] = fmt.Sprint(v)
@@ -281,3 +541,122 @@ func (u *Upstash) scan(ctx context.Context, key, cursor string, options ScanOpti
 		Items:  items,
 	}, nil
 }
+
+// flatMap converts a flat [key, value, key, value, ...] reply into a map.
+func flatMap(res any) map[string]any {
+	if m, ok := res.(map[string]any); ok {
+		return m
+	}
+	list, _ := res.([]any)
+	result := make(map[string]any, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		result[fmt.Sprint(list[i])] = list[i+1]
+	}
+	return result
+}
+
+// toInt converts a numeric reply into an int. Nil and unknown values yield 0.
+func toInt(v any) int {
+	return int(toInt64(v))
+}
+
+// toInt64 converts a numeric reply into an int64. Nil and unknown values yield 0.
+// Replies decoded with UseNumber arrive as json.Number and keep their full precision.
+func toInt64(v any) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			f, _ := n.Float64()
+			return int64(f)
+		}
+		return i
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+// unexpectedType returns an error wrapping ErrUnexpectedResponse for a reply to command of the wrong type.
+func unexpectedType(command string, v any) error {
+	return fmt.Errorf("%w: unexpected type %T for %s", ErrUnexpectedResponse, v, command)
+}
+
+// asInt is like toInt but returns an error for a reply to command that is not numeric.
+// Nil still yields 0, since several commands reply nil for a missing value.
+func asInt(command string, v any) (int, error) {
+	n, err := asInt64(command, v)
+	return int(n), err
+}
+
+// asInt64 is like toInt64 but returns an error for a reply to command that is not numeric.
+// Nil still yields 0, since several commands reply nil for a missing value.
+func asInt64(command string, v any) (int64, error) {
+	switch n := v.(type) {
+	case nil, float64, json.Number:
+		return toInt64(n), nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		if err != nil {
+			return 0, unexpectedType(command, v)
+		}
+		return i, nil
+	default:
+		return 0, unexpectedType(command, v)
+	}
+}
+
+// asFloat converts a numeric reply to command, which Redis usually sends as a string, into a float64.
+// Nil yields 0.
+func asFloat(command string, v any) (float64, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return n, nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		return 0, unexpectedType(command, v)
+	}
+}
+
+// asString returns the string reply to command, or an error if the reply is of another type.
+// Nil yields an empty string.
+func asString(command string, v any) (string, error) {
+	switch s := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return s, nil
+	default:
+		return "", unexpectedType(command, v)
+	}
+}
+
+// asList returns the array reply to command, or an error if the reply is of another type.
+// Nil yields a nil slice.
+func asList(command string, v any) ([]any, error) {
+	switch l := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return l, nil
+	default:
+		return nil, unexpectedType(command, v)
+	}
+}
+
+// toString converts a reply into a string. Nil yields an empty string.
+func toString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -2,6 +2,9 @@ package upstash
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math"
 )
 
 // SetBit sets or clears the bit at offset in the string value stored at key.
@@ -10,7 +13,7 @@ func (u *Upstash) SetBit(ctx context.Context, key string, offset int, value int)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SETBIT", res)
 }
 
 // GetBit returns the bit value at offset in the string value stored at key.
@@ -19,7 +22,7 @@ func (u *Upstash) GetBit(ctx context.Context, key string, offset int) (int, erro
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("GETBIT", res)
 }
 
 // BitCount counts the number of set bits (population counting) in a string.
@@ -28,7 +31,7 @@ func (u *Upstash) BitCount(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("BITCOUNT", res)
 }
 
 // BitOp performs a bitwise operation between multiple keys and stores the result in the destination key.
@@ -42,7 +45,7 @@ func (u *Upstash) BitOp(ctx context.Context, operation, destKey string, keys ...
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("BITOP", res)
 }
 
 // BitPos returns the position of the first bit set to 1 or 0 in a string.
@@ -56,7 +59,7 @@ func (u *Upstash) BitPos(ctx context.Context, key string, bit int, startEnd ...i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("BITPOS", res)
 }
 
 // BitField performs arbitrary bitfield integer operations on strings.
@@ -68,7 +71,38 @@ func (u *Upstash) BitField(ctx context.Context, key string, args ...any) ([]any,
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("BITFIELD", res)
+}
+
+// BitFieldInts is like BitField but converts each result to an int64.
+// Signed types (i8, i16, ...) may decode to negative values, unsigned types (u8, ..., u63) never do.
+// Numbers are decoded as float64 unless UseNumber is enabled, so values beyond 2^53 (large i64 and u63
+// fields) are only exact with UseNumber. Values that do not fit in an int64 are reported as an error
+// rather than silently overflowing. A nil result, returned for an operation skipped by OVERFLOW FAIL, decodes as 0.
+func (u *Upstash) BitFieldInts(ctx context.Context, key string, args ...any) ([]int64, error) {
+	res, err := u.BitField(ctx, key, args...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int64, len(res))
+	for i, v := range res {
+		switch n := v.(type) {
+		case nil:
+		case float64:
+			if n < math.MinInt64 || n >= math.MaxInt64 {
+				return nil, fmt.Errorf("bitfield result %d out of int64 range: %v", i, n)
+			}
+			result[i] = int64(n)
+		case json.Number:
+			result[i], err = n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("bitfield result %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected return type for bitfield result %d: %T", i, v)
+		}
+	}
+	return result, nil
 }
 
 // BitFieldRO is the read-only variant of BITFIELD.
@@ -80,5 +114,5 @@ func (u *Upstash) BitFieldRO(ctx context.Context, key string, args ...any) ([]an
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("BITFIELD_RO", res)
 }
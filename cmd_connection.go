@@ -2,6 +2,8 @@ package upstash
 
 import (
 	"context"
+	"fmt"
+	"strings"
 )
 
 // Ping returns PONG if no argument is provided, otherwise return a copy of the argument as a bulk.
@@ -14,7 +16,7 @@ func (u *Upstash) Ping(ctx context.Context, message ...string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("PING", res)
 }
 
 // Echo returns message.
@@ -23,5 +25,124 @@ func (u *Upstash) Echo(ctx context.Context, message string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("ECHO", res)
+}
+
+// Auth authenticates with password as the given ACL user, or as Options.Username if username is empty.
+// Without any username, the password of the default user is checked.
+// A rejected password is returned as an error wrapping ErrAuth.
+func (u *Upstash) Auth(ctx context.Context, username, password string) error {
+	if username == "" {
+		username = u.username
+	}
+	args := make([]any, 0, 2)
+	if username != "" {
+		args = append(args, username)
+	}
+	args = append(args, password)
+	_, err := u.Send(ctx, "AUTH", args...)
+	return err
+}
+
+// Select switches to the database with the given index.
+// Note: The REST API is stateless, so the switch only lasts for this request. To run commands
+// against another database, use Pipeline.Select or Multi.Select before queuing them.
+func (u *Upstash) Select(ctx context.Context, db int) error {
+	_, err := u.Send(ctx, "SELECT", db)
+	return err
+}
+
+// Reset resets the connection to its initial state.
+// Note: In REST API context, each request is independent, but added for parity.
+func (u *Upstash) Reset(ctx context.Context) (string, error) {
+	res, err := u.Send(ctx, "RESET")
+	if err != nil {
+		return "", err
+	}
+	return asString("RESET", res)
+}
+
+// Hello switches to the given protocol version and returns the server properties (server, version, proto, mode, role, modules...).
+// auth optionally carries a username and password to authenticate with; any other number of values is an error.
+// Note: The REST API abstracts the RESP protocol, so this mostly serves to read server information.
+func (u *Upstash) Hello(ctx context.Context, protover int, auth ...string) (map[string]any, error) {
+	args := []any{protover}
+	switch len(auth) {
+	case 0:
+	case 2:
+		args = append(args, "AUTH", auth[0], auth[1])
+	default:
+		return nil, fmt.Errorf("hello: auth must be a username and a password, got %d values", len(auth))
+	}
+	res, err := u.Send(ctx, "HELLO", args...)
+	if err != nil {
+		return nil, err
+	}
+	switch res.(type) {
+	case map[string]any, []any:
+		return flatMap(res), nil
+	default:
+		return nil, fmt.Errorf("unexpected return type for hello: %T", res)
+	}
+}
+
+// ClientList returns information about the client connections in the raw text format of CLIENT LIST,
+// one connection per line. It returns an error wrapping ErrNoPerm if the command is not permitted.
+func (u *Upstash) ClientList(ctx context.Context) (string, error) {
+	res, err := u.Send(ctx, "CLIENT", "LIST")
+	if err != nil {
+		return "", err
+	}
+	return asString("CLIENT LIST", res)
+}
+
+// ClientListParsed is like ClientList but parses the key=value tokens of each line into a map.
+func (u *Upstash) ClientListParsed(ctx context.Context) ([]map[string]string, error) {
+	list, err := u.ClientList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]string{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		client := make(map[string]string)
+		for _, token := range strings.Fields(line) {
+			k, v, _ := strings.Cut(token, "=")
+			client[k] = v
+		}
+		result = append(result, client)
+	}
+	return result, nil
+}
+
+// ClientKill closes the client connections matching all the given filters and returns their number.
+// It returns an error wrapping ErrNoPerm if the command is not permitted.
+func (u *Upstash) ClientKill(ctx context.Context, opts ClientKillOptions) (int, error) {
+	args := []any{"KILL"}
+	if opts.ID != 0 {
+		args = append(args, "ID", opts.ID)
+	}
+	if opts.Addr != "" {
+		args = append(args, "ADDR", opts.Addr)
+	}
+	if opts.LAddr != "" {
+		args = append(args, "LADDR", opts.LAddr)
+	}
+	if opts.User != "" {
+		args = append(args, "USER", opts.User)
+	}
+	if opts.Type != "" {
+		args = append(args, "TYPE", opts.Type)
+	}
+	if len(args) == 1 {
+		return 0, fmt.Errorf("client kill: no filter given")
+	}
+	res, err := u.Send(ctx, "CLIENT", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("CLIENT", res)
 }
@@ -2,6 +2,8 @@ package upstash
 
 import (
 	"context"
+	"fmt"
+	"unicode/utf8"
 )
 
 // FCall calls a function.
@@ -37,7 +39,7 @@ func (u *Upstash) FunctionLoad(ctx context.Context, payload string, replace bool
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("FUNCTION", res)
 }
 
 // FunctionList returns information about the libraries and functions.
@@ -50,7 +52,7 @@ func (u *Upstash) FunctionList(ctx context.Context, libraryName ...string) ([]an
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("FUNCTION", res)
 }
 
 // FunctionDelete deletes a library and all its functions.
@@ -59,7 +61,7 @@ func (u *Upstash) FunctionDelete(ctx context.Context, libraryName string) (strin
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("FUNCTION DELETE", res)
 }
 
 // FunctionFlush deletes all libraries and functions.
@@ -68,10 +70,56 @@ func (u *Upstash) FunctionFlush(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("FUNCTION FLUSH", res)
+}
+
+// FunctionKill kills the currently executing function, provided it has not performed any write yet.
+// It returns an error wrapping ErrNotBusy if no function is running.
+func (u *Upstash) FunctionKill(ctx context.Context) (string, error) {
+	res, err := u.Send(ctx, "FUNCTION", "KILL")
+	if err != nil {
+		return "", err
+	}
+	return asString("FUNCTION KILL", res)
 }
 
 // FunctionStats returns information about the current function execution.
 func (u *Upstash) FunctionStats(ctx context.Context) (any, error) {
 	return u.Send(ctx, "FUNCTION", "STATS")
 }
+
+// FunctionDump returns a serialized payload of all loaded libraries.
+// The payload is binary, so enable EnableBase64 to receive it without corruption.
+func (u *Upstash) FunctionDump(ctx context.Context) ([]byte, error) {
+	res, err := u.Send(ctx, "FUNCTION", "DUMP")
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+	s, err := asString("FUNCTION DUMP", res)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// FunctionRestore restores libraries from a payload returned by FunctionDump.
+// policy is one of FLUSH, APPEND or REPLACE and defaults to APPEND when empty.
+// Commands are sent as JSON, which cannot carry invalid UTF-8, so an error is returned instead of
+// silently corrupting such a payload. Real dumps usually contain invalid UTF-8 and cannot be restored this way.
+func (u *Upstash) FunctionRestore(ctx context.Context, payload []byte, policy string) (string, error) {
+	if !utf8.Valid(payload) {
+		return "", fmt.Errorf("function restore: payload must be valid UTF-8 to be sent over the REST API")
+	}
+	args := []any{"RESTORE", string(payload)}
+	if policy != "" {
+		args = append(args, policy)
+	}
+	res, err := u.Send(ctx, "FUNCTION", args...)
+	if err != nil {
+		return "", err
+	}
+	return asString("FUNCTION", res)
+}
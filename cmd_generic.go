@@ -2,7 +2,11 @@ package upstash
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/claywarren/upstash-go/internal/rest"
 )
@@ -46,7 +50,20 @@ func (u *Upstash) Del(ctx context.Context, keys ...string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("DEL", res)
+}
+
+// DelInt64 is like Del but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) DelInt64(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	res, err := u.Send(ctx, "DEL", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("DEL", res)
 }
 
 // Exists returns if key exists.
@@ -59,7 +76,20 @@ func (u *Upstash) Exists(ctx context.Context, keys ...string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("EXISTS", res)
+}
+
+// ExistsInt64 is like Exists but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) ExistsInt64(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	res, err := u.Send(ctx, "EXISTS", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("EXISTS", res)
 }
 
 // Expire sets a timeout on key.
@@ -68,7 +98,48 @@ func (u *Upstash) Expire(ctx context.Context, key string, seconds int) (int, err
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("EXPIRE", res)
+}
+
+// ExpireBool sets a timeout on key and reports whether it was set.
+func (u *Upstash) ExpireBool(ctx context.Context, key string, seconds int) (bool, error) {
+	res, err := u.Expire(ctx, key, seconds)
+	return res == 1, err
+}
+
+// ExpireStrict sets a timeout on key, returning ErrKeyNotFound if the key does not exist.
+// The existence check is pipelined with the EXPIRE, so it costs no extra round trip.
+func (u *Upstash) ExpireStrict(ctx context.Context, key string, seconds int) error {
+	pipe := u.Pipeline()
+	pipe.Push("EXPIRE", key, seconds)
+	pipe.Push("EXISTS", key)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if len(res) != 2 {
+		return fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	expired, err := pipelineResult(res[0])
+	if err != nil {
+		return err
+	}
+	exists, err := pipelineResult(res[1])
+	if err != nil {
+		return err
+	}
+	expiredN, err := asInt("EXPIRE", expired)
+	if err != nil {
+		return err
+	}
+	existsN, err := asInt("EXISTS", exists)
+	if err != nil {
+		return err
+	}
+	if expiredN == 0 && existsN == 0 {
+		return fmt.Errorf("expire %s: %w", key, ErrKeyNotFound)
+	}
+	return nil
 }
 
 // Ttl returns the remaining time to live of a key that has a timeout.
@@ -77,7 +148,7 @@ func (u *Upstash) Ttl(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("TTL", res)
 }
 
 // FlushAll deletes all keys of all existing databases.
@@ -93,13 +164,170 @@ func (u *Upstash) Scan(ctx context.Context, cursor string, options ScanOptions)
 	return u.scan(ctx, "", cursor, options, "SCAN")
 }
 
+// Bounds of the COUNT hint used by ScanAutoTune.
+const (
+	scanAutoTuneMinCount = 10
+	scanAutoTuneMaxCount = 1000
+)
+
+// ScanIterator pages through the results of SCAN, HSCAN, SSCAN or ZSCAN until the cursor returns to "0".
+// Call Next to advance, Key to read the current key and Err to check for errors once Next returns false.
+type ScanIterator struct {
+	ctx      context.Context
+	page     func(ctx context.Context, cursor string, options ScanOptions) (ScanResult, error)
+	options  ScanOptions
+	maxCount int // Upper bound of the doubling COUNT hint, or zero to keep it fixed.
+	cursor   string
+	started  bool
+	batch    []string
+	current  string
+	err      error
+}
+
+// ScanAutoTune returns an iterator over the keys matching options that tunes the COUNT hint as it goes.
+// The first page uses options.Count (10 if unset), and the hint doubles on each following page up to
+// 1000, so small keyspaces are answered quickly while large ones are walked in fewer round trips.
+func (u *Upstash) ScanAutoTune(ctx context.Context, options ScanOptions) *ScanIterator {
+	if options.Count <= 0 {
+		options.Count = scanAutoTuneMinCount
+	}
+	return &ScanIterator{
+		ctx:      ctx,
+		page:     u.Scan,
+		options:  options,
+		maxCount: max(scanAutoTuneMaxCount, options.Count),
+		cursor:   "0",
+	}
+}
+
+// ScanIterator returns an iterator over the keys matching options, paging with SCAN.
+func (u *Upstash) ScanIterator(ctx context.Context, options ScanOptions) *ScanIterator {
+	return &ScanIterator{ctx: ctx, page: u.Scan, options: options, cursor: "0"}
+}
+
+// HScanIterator returns an iterator over the hash stored at key, paging with HSCAN.
+// Like the HSCAN reply, it yields each field followed by its value.
+func (u *Upstash) HScanIterator(ctx context.Context, key string, options ScanOptions) *ScanIterator {
+	return u.keyScanIterator(ctx, key, options, u.HScan)
+}
+
+// SScanIterator returns an iterator over the members of the set stored at key, paging with SSCAN.
+func (u *Upstash) SScanIterator(ctx context.Context, key string, options ScanOptions) *ScanIterator {
+	return u.keyScanIterator(ctx, key, options, u.SScan)
+}
+
+// ZScanIterator returns an iterator over the sorted set stored at key, paging with ZSCAN.
+// Like the ZSCAN reply, it yields each member followed by its score.
+func (u *Upstash) ZScanIterator(ctx context.Context, key string, options ScanOptions) *ScanIterator {
+	return u.keyScanIterator(ctx, key, options, u.ZScan)
+}
+
+func (u *Upstash) keyScanIterator(ctx context.Context, key string, options ScanOptions, scan func(context.Context, string, string, ScanOptions) (ScanResult, error)) *ScanIterator {
+	page := func(ctx context.Context, cursor string, options ScanOptions) (ScanResult, error) {
+		return scan(ctx, key, cursor, options)
+	}
+	return &ScanIterator{ctx: ctx, page: page, options: options, cursor: "0"}
+}
+
+// Next advances the iterator to the next element. It returns false when the scan is complete or an error occurred.
+func (it *ScanIterator) Next() bool {
+	for len(it.batch) == 0 {
+		if it.err != nil || (it.started && it.cursor == "0") {
+			return false
+		}
+		page, err := it.page(it.ctx, it.cursor, it.options)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.started = true
+		it.cursor = page.Cursor
+		it.batch = page.Items
+		if it.maxCount > 0 {
+			it.options.Count = min(it.options.Count*2, it.maxCount)
+		}
+	}
+	it.current = it.batch[0]
+	it.batch = it.batch[1:]
+	return true
+}
+
+// Key returns the current key.
+func (it *ScanIterator) Key() string {
+	return it.current
+}
+
+// Val returns the current element. It is the same as Key, and reads better for hash, set and sorted set iterators.
+func (it *ScanIterator) Val() string {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *ScanIterator) Err() error {
+	return it.err
+}
+
+// DeleteByPattern removes all keys matching pattern, scanning batchSize keys at a time (100 if zero or negative)
+// and unlinking the keys of each batch in a single UNLINK. If progress is not nil it is called after each
+// batch with the number of keys deleted so far. It returns the total number of keys deleted.
+func (u *Upstash) DeleteByPattern(ctx context.Context, pattern string, batchSize int, progress func(deletedSoFar int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	options := ScanOptions{Match: pattern, Count: batchSize}
+	deleted := 0
+	cursor := "0"
+	for {
+		page, err := u.Scan(ctx, cursor, options)
+		if err != nil {
+			return deleted, err
+		}
+		if len(page.Items) > 0 {
+			n, err := u.Unlink(ctx, page.Items...)
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+			if progress != nil {
+				progress(deleted)
+			}
+		}
+		cursor = page.Cursor
+		if cursor == "0" {
+			return deleted, nil
+		}
+	}
+}
+
 // Copy copies the value stored at the source key to the destination key.
 func (u *Upstash) Copy(ctx context.Context, source, destination string) (int, error) {
 	res, err := u.Send(ctx, "COPY", source, destination)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("COPY", res)
+}
+
+// CopyIfType copies the value stored at the source key to the destination key,
+// but only if the source holds a value of the expected type.
+// It returns an error without copying if the type does not match.
+func (u *Upstash) CopyIfType(ctx context.Context, source, destination string, expected KeyType, replace bool) (int, error) {
+	t, err := u.Type(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+	if KeyType(t) != expected {
+		return 0, fmt.Errorf("copy: key %s has type %s, expected %s", source, t, expected)
+	}
+	args := []any{source, destination}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+	res, err := u.Send(ctx, "COPY", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("COPY", res)
 }
 
 // Dump returns a serialized version of the value stored at the specified key.
@@ -111,7 +339,7 @@ func (u *Upstash) Dump(ctx context.Context, key string) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("DUMP", res)
 }
 
 // ExpireAt sets an expiration time for a key using a Unix timestamp.
@@ -120,7 +348,7 @@ func (u *Upstash) ExpireAt(ctx context.Context, key string, timestamp int64) (in
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("EXPIREAT", res)
 }
 
 // Persist removes the expiration from a key.
@@ -129,7 +357,40 @@ func (u *Upstash) Persist(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("PERSIST", res)
+}
+
+// PersistAndGetOldTTL removes the expiration from key and returns the TTL, in seconds, it had before.
+// The TTL read and the PERSIST run in a single transaction, so no other command can change the TTL in between.
+// oldTTL follows the TTL command: -1 if the key had no expiry and -2 if it does not exist.
+func (u *Upstash) PersistAndGetOldTTL(ctx context.Context, key string) (removed bool, oldTTL int, err error) {
+	tx := u.Multi()
+	tx.Push("TTL", key)
+	tx.Push("PERSIST", key)
+	res, err := tx.Exec(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected transaction result length: %d", len(res))
+	}
+	ttl, err := pipelineResult(res[0])
+	if err != nil {
+		return false, 0, err
+	}
+	persisted, err := pipelineResult(res[1])
+	if err != nil {
+		return false, 0, err
+	}
+	oldTTL, err = asInt("TTL", ttl)
+	if err != nil {
+		return false, 0, err
+	}
+	persistedN, err := asInt("PERSIST", persisted)
+	if err != nil {
+		return false, 0, err
+	}
+	return persistedN == 1, oldTTL, nil
 }
 
 // PExpire sets a timeout on key in milliseconds.
@@ -138,7 +399,7 @@ func (u *Upstash) PExpire(ctx context.Context, key string, milliseconds int64) (
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("PEXPIRE", res)
 }
 
 // PTtl returns the remaining time to live of a key that has a timeout in milliseconds.
@@ -147,7 +408,7 @@ func (u *Upstash) PTtl(ctx context.Context, key string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int64(res.(float64)), nil
+	return asInt64("PTTL", res)
 }
 
 // RandomKey returns a random key from the currently selected database.
@@ -159,7 +420,7 @@ func (u *Upstash) RandomKey(ctx context.Context) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("RANDOMKEY", res)
 }
 
 // Rename renames key to newkey.
@@ -174,7 +435,13 @@ func (u *Upstash) RenameNX(ctx context.Context, key, newkey string) (int, error)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("RENAMENX", res)
+}
+
+// RenameNXBool renames key to newkey if the new key does not yet exist and reports whether it was renamed.
+func (u *Upstash) RenameNXBool(ctx context.Context, key, newkey string) (bool, error) {
+	res, err := u.RenameNX(ctx, key, newkey)
+	return res == 1, err
 }
 
 // Touch alters the last access time of a key(s).
@@ -187,7 +454,7 @@ func (u *Upstash) Touch(ctx context.Context, keys ...string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("TOUCH", res)
 }
 
 // Type returns the string representation of the type of the value stored at key.
@@ -196,7 +463,7 @@ func (u *Upstash) Type(ctx context.Context, key string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("TYPE", res)
 }
 
 // Unlink removes the specified keys. A key is ignored if it does not exist.
@@ -209,7 +476,7 @@ func (u *Upstash) Unlink(ctx context.Context, keys ...string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("UNLINK", res)
 }
 
 // Migrate atomically transfers a key from a Redis instance to another one.
@@ -232,7 +499,7 @@ func (u *Upstash) Migrate(ctx context.Context, host, port, key, destinationDB st
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("MIGRATE", res)
 }
 
 // Object returns various information about a key.
@@ -240,6 +507,79 @@ func (u *Upstash) Object(ctx context.Context, subcommand, key string) (any, erro
 	return u.Send(ctx, "OBJECT", subcommand, key)
 }
 
+// ObjectEncoding returns the internal encoding used to store the value at key, e.g. "listpack" or "skiplist".
+func (u *Upstash) ObjectEncoding(ctx context.Context, key string) (string, error) {
+	res, err := u.Object(ctx, "ENCODING", key)
+	if err != nil {
+		return "", err
+	}
+	return toString(res), nil
+}
+
+// ObjectIdleTime returns the time since the value stored at key was last accessed, with a resolution of seconds.
+// It requires the server to use an LRU maxmemory-policy.
+func (u *Upstash) ObjectIdleTime(ctx context.Context, key string) (time.Duration, error) {
+	res, err := u.Object(ctx, "IDLETIME", key)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := asInt64("OBJECT IDLETIME", res)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// IdleKeys returns the keys that have not been accessed for at least minIdle, in the order given.
+// The idle times are read with a single pipeline of OBJECT IDLETIME commands; keys that do not exist are skipped.
+func (u *Upstash) IdleKeys(ctx context.Context, keys []string, minIdle time.Duration) ([]string, error) {
+	result := []string{}
+	if len(keys) == 0 {
+		return result, nil
+	}
+	pipe := u.Pipeline()
+	for _, k := range keys {
+		pipe.Push("OBJECT", "IDLETIME", k)
+	}
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) != len(keys) {
+		return nil, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	for i, r := range res {
+		idle, err := pipelineResult(r)
+		if err != nil {
+			return nil, fmt.Errorf("object idletime %s: %w", keys[i], err)
+		}
+		if idle == nil {
+			continue
+		}
+		seconds, err := asInt64("OBJECT IDLETIME", idle)
+		if err != nil {
+			return nil, err
+		}
+		if time.Duration(seconds)*time.Second >= minIdle {
+			result = append(result, keys[i])
+		}
+	}
+	return result, nil
+}
+
+// AccessFrequency returns the logarithmic access frequency counter of the value stored at key.
+// It requires the server to use an LFU maxmemory-policy.
+func (u *Upstash) AccessFrequency(ctx context.Context, key string) (int64, error) {
+	res, err := u.Object(ctx, "FREQ", key)
+	if err != nil {
+		if strings.Contains(err.Error(), "LFU") {
+			return 0, fmt.Errorf("object freq requires an LFU maxmemory-policy: %w", err)
+		}
+		return 0, err
+	}
+	return asInt64("OBJECT FREQ", res)
+}
+
 // Sort returns or stores the elements in a list, set or sorted set.
 func (u *Upstash) Sort(ctx context.Context, key string, args ...any) (any, error) {
 	fullArgs := make([]any, 0, 1+len(args))
@@ -248,6 +588,64 @@ func (u *Upstash) Sort(ctx context.Context, key string, args ...any) (any, error
 	return u.Send(ctx, "SORT", fullArgs...)
 }
 
+// SortWithOptions returns the elements in a list, set or sorted set sorted according to options.
+// With several Get patterns the values fetched for each element are interleaved; see SortGrouped.
+// Missing external keys are returned as empty strings.
+func (u *Upstash) SortWithOptions(ctx context.Context, key string, options SortOptions) ([]string, error) {
+	res, err := u.Send(ctx, "SORT", sortArgs(key, options)...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("SORT", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// SortGrouped is like SortWithOptions but groups the reply into one slice per sorted element,
+// holding the value fetched by each Get pattern in order. Without Get patterns each group holds
+// just the element.
+func (u *Upstash) SortGrouped(ctx context.Context, key string, options SortOptions) ([][]string, error) {
+	values, err := u.SortWithOptions(ctx, key, options)
+	if err != nil {
+		return nil, err
+	}
+	size := max(len(options.Get), 1)
+	if len(values)%size != 0 {
+		return nil, fmt.Errorf("%w: %d SORT values for %d GET patterns", ErrUnexpectedResponse, len(values), size)
+	}
+	result := make([][]string, 0, len(values)/size)
+	for i := 0; i < len(values); i += size {
+		result = append(result, values[i:i+size:i+size])
+	}
+	return result, nil
+}
+
+func sortArgs(key string, options SortOptions) []any {
+	args := []any{key}
+	if options.By != "" {
+		args = append(args, "BY", options.By)
+	}
+	if options.Limit != nil {
+		args = append(args, "LIMIT", options.Limit.Offset, options.Limit.Count)
+	}
+	for _, pattern := range options.Get {
+		args = append(args, "GET", pattern)
+	}
+	if options.Desc {
+		args = append(args, "DESC")
+	}
+	if options.Alpha {
+		args = append(args, "ALPHA")
+	}
+	return args
+}
+
 // SortRO is the read-only variant of SORT.
 func (u *Upstash) SortRO(ctx context.Context, key string, args ...any) (any, error) {
 	fullArgs := make([]any, 0, 1+len(args))
@@ -262,7 +660,7 @@ func (u *Upstash) ExpireTime(ctx context.Context, key string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int64(res.(float64)), nil
+	return asInt64("EXPIRETIME", res)
 }
 
 // PExpireTime returns the absolute Unix timestamp (in milliseconds) at which the given key will expire.
@@ -271,7 +669,7 @@ func (u *Upstash) PExpireTime(ctx context.Context, key string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int64(res.(float64)), nil
+	return asInt64("PEXPIRETIME", res)
 }
 
 // Wait blocks the current client until all the previous write commands are successfully transferred and acknowledged by at least the specified number of replicas.
@@ -280,7 +678,104 @@ func (u *Upstash) Wait(ctx context.Context, numReplicas int, timeout int64) (int
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("WAIT", res)
+}
+
+// waitGrace is how long WaitDetailed keeps waiting for the reply past the context deadline,
+// covering the round trip of a WAIT whose server timeout was clamped to that deadline.
+const waitGrace = 5 * time.Second
+
+// WaitDetailed is like Wait but clamps the server timeout to the context deadline, so that the server
+// replies by the deadline instead of the request being aborted and the replica count lost.
+// If the deadline passes while that reply is in flight, WaitDetailed keeps waiting for it for at most
+// waitGrace. An explicit cancellation of ctx returns immediately with TimedOut set and ctx.Err(),
+// leaving the request to complete in the background.
+// TimedOut reports whether fewer than numReplicas replicas acknowledged the writes.
+func (u *Upstash) WaitDetailed(ctx context.Context, numReplicas int, timeout int64) (WaitResult, error) {
+	var sendCtx context.Context
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline).Milliseconds()
+		if remaining < 1 {
+			return WaitResult{TimedOut: true}, context.DeadlineExceeded
+		}
+		if timeout == 0 || timeout > remaining {
+			timeout = remaining
+		}
+		sendCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), time.Duration(timeout)*time.Millisecond+waitGrace)
+	} else {
+		sendCtx, cancel = context.WithCancel(ctx)
+	}
+
+	type waitReply struct {
+		res any
+		err error
+	}
+	replies := make(chan waitReply, 1)
+	go func() {
+		defer cancel()
+		res, err := u.Send(sendCtx, "WAIT", numReplicas, timeout)
+		replies <- waitReply{res: res, err: err}
+	}()
+
+	var reply waitReply
+	select {
+	case reply = <-replies:
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return WaitResult{TimedOut: true}, ctx.Err()
+		}
+		// The server timeout was clamped to the deadline, so the reply is already on its way.
+		reply = <-replies
+	}
+	if reply.err != nil {
+		return WaitResult{TimedOut: true}, reply.err
+	}
+	acked, err := asInt("WAIT", reply.res)
+	if err != nil {
+		return WaitResult{}, err
+	}
+	return WaitResult{Acknowledged: acked, TimedOut: acked < numReplicas}, nil
+}
+
+// WaitAOF blocks until all the previous write commands are fsynced to the AOF of the local server
+// and of at least numReplicas replicas, or until timeout milliseconds have elapsed.
+// It returns the number of local servers (0 or 1) and replicas that acknowledged the fsync.
+func (u *Upstash) WaitAOF(ctx context.Context, numLocal, numReplicas int, timeout int64) (local, replicas int, err error) {
+	res, err := u.Send(ctx, "WAITAOF", numLocal, numReplicas, timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	list, ok := res.([]any)
+	if !ok || len(list) != 2 {
+		return 0, 0, fmt.Errorf("unexpected return type for waitaof: %T", res)
+	}
+	if local, err = asInt("WAITAOF", list[0]); err != nil {
+		return 0, 0, err
+	}
+	if replicas, err = asInt("WAITAOF", list[1]); err != nil {
+		return 0, 0, err
+	}
+	return local, replicas, nil
+}
+
+// EnsureDurable is a durability barrier for the previous write commands: it issues WAIT 1 and
+// returns an error unless at least one replica acknowledged them within timeout.
+// The timeout is sent in whole milliseconds, with a minimum of one, since zero would block forever.
+// Use WaitAOF instead when the database relies on AOF persistence rather than replication.
+func (u *Upstash) EnsureDurable(ctx context.Context, timeout time.Duration) error {
+	ms := timeout.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	acked, err := u.Wait(ctx, 1, ms)
+	if err != nil {
+		return err
+	}
+	if acked < 1 {
+		return fmt.Errorf("writes not acknowledged by any replica within %s", timeout)
+	}
+	return nil
 }
 
 // Move moves a key from the currently selected database to the specified destination database.
@@ -289,7 +784,13 @@ func (u *Upstash) Move(ctx context.Context, key string, db int) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("MOVE", res)
+}
+
+// MoveBool moves a key to the specified destination database and reports whether it was moved.
+func (u *Upstash) MoveBool(ctx context.Context, key string, db int) (bool, error) {
+	res, err := u.Move(ctx, key, db)
+	return res == 1, err
 }
 
 // Restore creates a key associated with a value that is obtained by deserializing the provided serialized value.
@@ -302,5 +803,33 @@ func (u *Upstash) Restore(ctx context.Context, key string, ttl int64, serialized
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("RESTORE", res)
+}
+
+// RestoreWithOptions creates a key associated with a value that is obtained by deserializing the provided serialized value,
+// with additional options.
+// Commands are sent as JSON, which cannot carry invalid UTF-8, so an error is returned instead of
+// silently corrupting such a payload. DUMP payloads often contain invalid UTF-8 and cannot be restored this way.
+func (u *Upstash) RestoreWithOptions(ctx context.Context, key string, ttl int64, data []byte, options RestoreOptions) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("restore: payload must be valid UTF-8 to be sent over the REST API")
+	}
+	args := []any{key, ttl, string(data)}
+	if options.Replace {
+		args = append(args, "REPLACE")
+	}
+	if options.AbsTTL {
+		args = append(args, "ABSTTL")
+	}
+	if options.IdleTime != nil {
+		args = append(args, "IDLETIME", *options.IdleTime)
+	}
+	if options.Freq != nil {
+		args = append(args, "FREQ", *options.Freq)
+	}
+	res, err := u.Send(ctx, "RESTORE", args...)
+	if err != nil {
+		return "", err
+	}
+	return asString("RESTORE", res)
 }
@@ -2,6 +2,7 @@ package upstash
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 )
 
@@ -16,7 +17,85 @@ func (u *Upstash) GeoAdd(ctx context.Context, key string, locations ...GeoLocati
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("GEOADD", res)
+}
+
+// GeoAddWithOptions adds the specified geospatial items to the specified key with additional options.
+// Combine XX and CH to move existing members and learn how many actually changed position.
+func (u *Upstash) GeoAddWithOptions(ctx context.Context, key string, options GeoAddOptions, locations ...GeoLocation) (int, error) {
+	res, err := u.Send(ctx, "GEOADD", geoAddArgs(key, options, locations)...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("GEOADD", res)
+}
+
+// GeoAddWithResult is like GeoAddWithOptions, but with TrackChanges set it also reports which members
+// were added or moved. The positions are read with GEOPOS before and after the GEOADD within a single
+// transaction, so concurrent writers cannot skew the diff.
+func (u *Upstash) GeoAddWithResult(ctx context.Context, key string, options GeoAddOptions, locations ...GeoLocation) (GeoAddResult, error) {
+	if !options.TrackChanges {
+		n, err := u.GeoAddWithOptions(ctx, key, options, locations...)
+		return GeoAddResult{Count: n}, err
+	}
+
+	posArgs := make([]any, 0, 1+len(locations))
+	posArgs = append(posArgs, key)
+	for _, loc := range locations {
+		posArgs = append(posArgs, loc.Member)
+	}
+	tx := u.Multi()
+	tx.Push("GEOPOS", posArgs...)
+	tx.Push("GEOADD", geoAddArgs(key, options, locations)...)
+	tx.Push("GEOPOS", posArgs...)
+	res, err := tx.Exec(ctx)
+	if err != nil {
+		return GeoAddResult{}, err
+	}
+	if len(res) != 3 {
+		return GeoAddResult{}, fmt.Errorf("unexpected transaction result length: %d", len(res))
+	}
+	results := make([]any, len(res))
+	for i, r := range res {
+		results[i], err = pipelineResult(r)
+		if err != nil {
+			return GeoAddResult{}, err
+		}
+	}
+	before, _ := results[0].([]any)
+	after, _ := results[2].([]any)
+	if len(before) != len(locations) || len(after) != len(locations) {
+		return GeoAddResult{}, fmt.Errorf("unexpected return type for geopos: %T", results[0])
+	}
+
+	count, err := asInt("GEOADD", results[1])
+	if err != nil {
+		return GeoAddResult{}, err
+	}
+	result := GeoAddResult{Count: count, Changed: []string{}}
+	for i, loc := range locations {
+		if fmt.Sprint(before[i]) != fmt.Sprint(after[i]) {
+			result.Changed = append(result.Changed, loc.Member)
+		}
+	}
+	return result, nil
+}
+
+func geoAddArgs(key string, options GeoAddOptions, locations []GeoLocation) []any {
+	args := make([]any, 0, 3+len(locations)*3)
+	args = append(args, key)
+	if options.NX {
+		args = append(args, "NX")
+	} else if options.XX {
+		args = append(args, "XX")
+	}
+	if options.CH {
+		args = append(args, "CH")
+	}
+	for _, loc := range locations {
+		args = append(args, loc.Longitude, loc.Latitude, loc.Member)
+	}
+	return args
 }
 
 // GeoDist returns the distance between two members in the geospatial index.
@@ -28,7 +107,7 @@ func (u *Upstash) GeoDist(ctx context.Context, key, member1, member2, unit strin
 	if res == nil {
 		return 0, nil
 	}
-	return strconv.ParseFloat(res.(string), 64)
+	return asFloat("GEODIST", res)
 }
 
 // GeoPos returns the longitude and latitude of all the specified members.
@@ -42,15 +121,21 @@ func (u *Upstash) GeoPos(ctx context.Context, key string, members ...string) ([]
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("GEOPOS", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([][2]float64, len(list))
 	for i, v := range list {
 		if v == nil {
 			continue
 		}
-		pos := v.([]any)
-		lng, _ := strconv.ParseFloat(pos[0].(string), 64)
-		lat, _ := strconv.ParseFloat(pos[1].(string), 64)
+		pos, ok := v.([]any)
+		if !ok || len(pos) < 2 {
+			return nil, unexpectedType("GEOPOS", v)
+		}
+		lng, _ := strconv.ParseFloat(toString(pos[0]), 64)
+		lat, _ := strconv.ParseFloat(toString(pos[1]), 64)
 		result[i] = [2]float64{lng, lat}
 	}
 	return result, nil
@@ -67,10 +152,13 @@ func (u *Upstash) GeoHash(ctx context.Context, key string, members ...string) ([
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("GEOHASH", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -81,10 +169,13 @@ func (u *Upstash) GeoRadiusByMember(ctx context.Context, key, member string, rad
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("GEORADIUSBYMEMBER", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -106,5 +197,45 @@ func (u *Upstash) GeoSearchStore(ctx context.Context, destination, source string
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("GEOSEARCHSTORE", res)
+}
+
+// GeoSearchStoreTyped is like GeoSearchStore, but takes typed options.
+// If storeDist is true, the members are stored with their distance from the center as score,
+// instead of their geohash, so destination is no longer a geospatial index.
+// It returns the number of members stored.
+func (u *Upstash) GeoSearchStoreTyped(ctx context.Context, destination, source string, options GeoSearchOptions, storeDist bool) (int, error) {
+	args := geoSearchArgs(options)
+	if storeDist {
+		args = append(args, "STOREDIST")
+	}
+	return u.GeoSearchStore(ctx, destination, source, args...)
+}
+
+func geoSearchArgs(options GeoSearchOptions) []any {
+	args := make([]any, 0, 10)
+	if options.FromLonLat != nil {
+		args = append(args, "FROMLONLAT", options.FromLonLat[0], options.FromLonLat[1])
+	} else {
+		args = append(args, "FROMMEMBER", options.FromMember)
+	}
+	unit := options.Unit
+	if unit == "" {
+		unit = "m"
+	}
+	if options.ByBox != nil {
+		args = append(args, "BYBOX", options.ByBox[0], options.ByBox[1], unit)
+	} else {
+		args = append(args, "BYRADIUS", options.ByRadius, unit)
+	}
+	if options.Sort != "" {
+		args = append(args, options.Sort)
+	}
+	if options.Count > 0 {
+		args = append(args, "COUNT", options.Count)
+		if options.Any {
+			args = append(args, "ANY")
+		}
+	}
+	return args
 }
@@ -2,7 +2,8 @@ package upstash
 
 import (
 	"context"
-	"strconv"
+	"fmt"
+	"unicode/utf8"
 )
 
 // HSet sets the string value of a hash field.
@@ -11,7 +12,7 @@ func (u *Upstash) HSet(ctx context.Context, key, field, value string) (int, erro
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HSET", res)
 }
 
 // HGet returns the value associated with field in the hash stored at key.
@@ -23,7 +24,64 @@ func (u *Upstash) HGet(ctx context.Context, key, field string) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("HGET", res)
+}
+
+// HSetMulti sets multiple fields of the hash stored at key.
+// It returns the number of fields that were newly created; overwritten fields are not counted.
+func (u *Upstash) HSetMulti(ctx context.Context, key string, fields []KV) (int, error) {
+	args := make([]any, 0, 1+len(fields)*2)
+	args = append(args, key)
+	for _, kv := range fields {
+		args = append(args, kv.Key, kv.Value)
+	}
+	res, err := u.Send(ctx, "HSET", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("HSET", res)
+}
+
+// HSetBool sets the string value of a hash field and reports whether the field was newly created.
+// It returns false if an existing field was overwritten.
+func (u *Upstash) HSetBool(ctx context.Context, key, field, value string) (bool, error) {
+	res, err := u.HSet(ctx, key, field, value)
+	return res == 1, err
+}
+
+// HSetBytes sets the value of a hash field, both given as raw bytes.
+// Commands are sent as JSON, which cannot carry invalid UTF-8, so an error is returned
+// instead of silently corrupting such field names or values. Combine with EnableBase64
+// so that field names and values returned by HGetBytes, HKeys and HGetAll are decoded symmetrically.
+func (u *Upstash) HSetBytes(ctx context.Context, key string, field, value []byte) (int, error) {
+	if !utf8.Valid(field) || !utf8.Valid(value) {
+		return 0, fmt.Errorf("hset: field and value must be valid UTF-8 to be sent over the REST API")
+	}
+	res, err := u.Send(ctx, "HSET", key, string(field), string(value))
+	if err != nil {
+		return 0, err
+	}
+	return asInt("HSET", res)
+}
+
+// HGetBytes returns the value associated with field in the hash stored at key as raw bytes.
+// It returns nil if the field does not exist. See HSetBytes for the limitations on field names.
+func (u *Upstash) HGetBytes(ctx context.Context, key string, field []byte) ([]byte, error) {
+	if !utf8.Valid(field) {
+		return nil, fmt.Errorf("hget: field must be valid UTF-8 to be sent over the REST API")
+	}
+	res, err := u.Send(ctx, "HGET", key, string(field))
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+	s, err := asString("HGET", res)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
 }
 
 // HGetAll returns all fields and values of the hash stored at key.
@@ -32,10 +90,13 @@ func (u *Upstash) HGetAll(ctx context.Context, key string) (map[string]string, e
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("HGETALL", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make(map[string]string, len(list)/2)
 	for i := 0; i < len(list); i += 2 {
-		result[list[i].(string)] = list[i+1].(string)
+		result[toString(list[i])] = toString(list[i+1])
 	}
 	return result, nil
 }
@@ -51,7 +112,7 @@ func (u *Upstash) HDel(ctx context.Context, key string, fields ...string) (int,
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HDEL", res)
 }
 
 // HLen returns the number of fields contained in the hash stored at key.
@@ -60,7 +121,7 @@ func (u *Upstash) HLen(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HLEN", res)
 }
 
 // HScan iterates over fields of a hash.
@@ -68,13 +129,40 @@ func (u *Upstash) HScan(ctx context.Context, key, cursor string, options ScanOpt
 	return u.scan(ctx, key, cursor, options, "HSCAN")
 }
 
+// HScanAll iterates over all fields of a hash using HSCAN and returns them as a map.
+// It is a safe alternative to HGetAll for very large hashes, but it is not atomic:
+// fields changed while scanning may be missed or reported with stale values.
+func (u *Upstash) HScanAll(ctx context.Context, key string, options ScanOptions) (map[string]string, error) {
+	result := make(map[string]string)
+	cursor := "0"
+	for {
+		page, err := u.HScan(ctx, key, cursor, options)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(page.Items); i += 2 {
+			result[page.Items[i]] = page.Items[i+1]
+		}
+		cursor = page.Cursor
+		if cursor == "0" {
+			return result, nil
+		}
+	}
+}
+
 // HExists returns if field is an existing field in the hash stored at key.
 func (u *Upstash) HExists(ctx context.Context, key, field string) (int, error) {
 	res, err := u.Send(ctx, "HEXISTS", key, field)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HEXISTS", res)
+}
+
+// HExistsBool reports whether field is an existing field in the hash stored at key.
+func (u *Upstash) HExistsBool(ctx context.Context, key, field string) (bool, error) {
+	res, err := u.HExists(ctx, key, field)
+	return res == 1, err
 }
 
 // HIncrBy increments the integer value of a hash field by the given number.
@@ -83,7 +171,7 @@ func (u *Upstash) HIncrBy(ctx context.Context, key, field string, increment int)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HINCRBY", res)
 }
 
 // HIncrByFloat increments the float value of a hash field by the given amount.
@@ -92,7 +180,7 @@ func (u *Upstash) HIncrByFloat(ctx context.Context, key, field string, increment
 	if err != nil {
 		return 0, err
 	}
-	return strconv.ParseFloat(res.(string), 64)
+	return asFloat("HINCRBYFLOAT", res)
 }
 
 // HKeys returns all field names in the hash stored at key.
@@ -101,10 +189,13 @@ func (u *Upstash) HKeys(ctx context.Context, key string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("HKEYS", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -120,13 +211,16 @@ func (u *Upstash) HMGet(ctx context.Context, key string, fields ...string) ([]st
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("HMGET", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
 		if v == nil {
 			result[i] = ""
 		} else {
-			result[i] = v.(string)
+			result[i] = toString(v)
 		}
 	}
 	return result, nil
@@ -143,7 +237,7 @@ func (u *Upstash) HMSet(ctx context.Context, key string, kv map[string]string) (
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("HMSET", res)
 }
 
 // HSetNX sets the value of a hash field, only if the field does not yet exist.
@@ -152,7 +246,7 @@ func (u *Upstash) HSetNX(ctx context.Context, key, field, value string) (int, er
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HSETNX", res)
 }
 
 // HStrLen returns the string length of the value associated with field in the hash stored at key.
@@ -161,7 +255,7 @@ func (u *Upstash) HStrLen(ctx context.Context, key, field string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("HSTRLEN", res)
 }
 
 // HVals returns all values in the hash stored at key.
@@ -170,10 +264,56 @@ func (u *Upstash) HVals(ctx context.Context, key string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("HVALS", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// HRandField returns count random field names from the hash stored at key.
+// A positive count returns distinct fields; a negative count may return the same field multiple times.
+func (u *Upstash) HRandField(ctx context.Context, key string, count int) ([]string, error) {
+	res, err := u.Send(ctx, "HRANDFIELD", key, count)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return []string{}, nil
+	}
+	list, err := asList("HRANDFIELD", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// HRandFieldWithValues returns count random fields, with their values, from the hash stored at key.
+// A negative count may return the same field multiple times, so the result is a slice
+// that preserves order and duplicates rather than a map.
+func (u *Upstash) HRandFieldWithValues(ctx context.Context, key string, count int) ([]KV, error) {
+	res, err := u.Send(ctx, "HRANDFIELD", key, count, "WITHVALUES")
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return []KV{}, nil
+	}
+	list, err := asList("HRANDFIELD", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]KV, 0, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		result = append(result, KV{Key: toString(list[i]), Value: toString(list[i+1])})
 	}
 	return result, nil
 }
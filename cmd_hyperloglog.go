@@ -2,6 +2,9 @@ package upstash
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 )
 
 // PFAdd adds elements to a HyperLogLog.
@@ -15,7 +18,7 @@ func (u *Upstash) PFAdd(ctx context.Context, key string, elements ...string) (in
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("PFADD", res)
 }
 
 // PFCount returns the approximated cardinality of the HyperLogLog(s).
@@ -28,7 +31,7 @@ func (u *Upstash) PFCount(ctx context.Context, keys ...string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("PFCOUNT", res)
 }
 
 // PFMerge merges multiple HyperLogLogs into one.
@@ -41,3 +44,59 @@ func (u *Upstash) PFMerge(ctx context.Context, dest string, sources ...string) e
 	_, err := u.Send(ctx, "PFMERGE", args...)
 	return err
 }
+
+// UniqueCountWindow adds elements to the HyperLogLog of the current time bucket and returns its
+// approximated cardinality. Buckets are aligned to multiples of window since the Unix epoch and
+// stored at "baseKey:<bucket>", where bucket is the current Unix time in milliseconds divided by
+// the window in milliseconds. Each bucket key is kept for retain windows, expiring retain windows
+// after its own window started: with retain 1 it expires when its window ends, and with retain N
+// the buckets of the last N windows, including the current one, remain available.
+func (u *Upstash) UniqueCountWindow(ctx context.Context, baseKey string, elements []string, window time.Duration, retain int) (int, error) {
+	windowMs := window.Milliseconds()
+	if windowMs <= 0 {
+		return 0, fmt.Errorf("invalid window: %s", window)
+	}
+	if retain < 1 {
+		return 0, fmt.Errorf("invalid retention: %d windows", retain)
+	}
+	bucket := time.Now().UnixMilli() / windowMs
+	key := baseKey + ":" + strconv.FormatInt(bucket, 10)
+
+	args := make([]any, 0, 1+len(elements))
+	args = append(args, key)
+	for _, e := range elements {
+		args = append(args, e)
+	}
+	pipe := u.Pipeline()
+	pipe.Push("PFADD", args...)
+	pipe.Push("PEXPIREAT", key, (bucket+int64(retain))*windowMs)
+	pipe.Push("PFCOUNT", key)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 3 {
+		return 0, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	for _, r := range res[:2] {
+		if _, err := pipelineResult(r); err != nil {
+			return 0, err
+		}
+	}
+	count, err := pipelineResult(res[2])
+	if err != nil {
+		return 0, err
+	}
+	return asInt("PFCOUNT", count)
+}
+
+// UniqueCountMerged returns the approximated cardinality of the union of the given HyperLogLogs,
+// e.g. the buckets written by UniqueCountWindow over the last N windows, provided they were written
+// with a retain of at least N. Keys that have expired count as empty.
+// Unlike PFMerge it does not modify any key.
+func (u *Upstash) UniqueCountMerged(ctx context.Context, keys []string) (int, error) {
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("no keys given")
+	}
+	return u.PFCount(ctx, keys...)
+}
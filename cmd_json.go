@@ -2,7 +2,10 @@ package upstash
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 )
 
 // JsonSet sets the JSON value at path in key.
@@ -11,7 +14,20 @@ func (u *Upstash) JsonSet(ctx context.Context, key, path string, value any) (str
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("JSON.SET", res)
+}
+
+// JsonMSet sets the JSON values of the given entries atomically, in a single JSON.MSET.
+func (u *Upstash) JsonMSet(ctx context.Context, entries []JsonSetEntry) (string, error) {
+	args := make([]any, 0, 3*len(entries))
+	for _, e := range entries {
+		args = append(args, e.Key, e.Path, e.Value)
+	}
+	res, err := u.Send(ctx, "JSON.MSET", args...)
+	if err != nil {
+		return "", err
+	}
+	return asString("JSON.MSET", res)
 }
 
 // JsonGet returns the value at path in key.
@@ -24,13 +40,50 @@ func (u *Upstash) JsonGet(ctx context.Context, key string, paths ...string) (any
 	return u.Send(ctx, "JSON.GET", args...)
 }
 
+// JsonGetSingle returns the decoded value at path in key. JSONPath results matching exactly
+// one value are unwrapped from the array RedisJSON puts them in; multiple matches are returned
+// as a []any. It returns ErrNil if the key does not exist or the path matched nothing.
+func (u *Upstash) JsonGetSingle(ctx context.Context, key, path string) (any, error) {
+	res, err := u.JsonGet(ctx, key, path)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, ErrNil
+	}
+	s, err := asString("JSON.GET", res)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return nil, fmt.Errorf("unable to decode json: %w", err)
+	}
+	// Legacy paths not starting with "$" return the value itself rather than an array of matches.
+	if !strings.HasPrefix(path, "$") {
+		return value, nil
+	}
+	matches, ok := value.([]any)
+	if !ok {
+		return value, nil
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrNil
+	case 1:
+		return matches[0], nil
+	default:
+		return matches, nil
+	}
+}
+
 // JsonDel deletes the value at path in key.
 func (u *Upstash) JsonDel(ctx context.Context, key, path string) (int, error) {
 	res, err := u.Send(ctx, "JSON.DEL", key, path)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("JSON.DEL", res)
 }
 
 // JsonMGet returns the values at path in multiple keys.
@@ -44,7 +97,7 @@ func (u *Upstash) JsonMGet(ctx context.Context, path string, keys ...string) ([]
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("JSON.MGET", res)
 }
 
 // JsonType returns the type of the JSON value at path in key.
@@ -53,7 +106,7 @@ func (u *Upstash) JsonType(ctx context.Context, key, path string) (string, error
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("JSON.TYPE", res)
 }
 
 // JsonArrAppend appends the JSON values to the array at path in key.
@@ -65,14 +118,7 @@ func (u *Upstash) JsonArrAppend(ctx context.Context, key, path string, values ..
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
-		}
-	}
-	return result, nil
+	return u.parseIntSlice("JSON.ARRAPPEND", res)
 }
 
 // JsonArrLen returns the length of the array at path in key.
@@ -81,14 +127,17 @@ func (u *Upstash) JsonArrLen(ctx context.Context, key, path string) ([]int, erro
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
-		}
+	return u.parseIntSlice("JSON.ARRLEN", res)
+}
+
+// JsonArrLenPtr is like JsonArrLen but returns nil for each path match that is not an array,
+// distinguishing it from an empty array.
+func (u *Upstash) JsonArrLenPtr(ctx context.Context, key, path string) ([]*int, error) {
+	res, err := u.Send(ctx, "JSON.ARRLEN", key, path)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return u.parseIntPtrSlice("JSON.ARRLEN", res)
 }
 
 // JsonClear removes container values (list, set, hash) or zeros numeric values.
@@ -102,7 +151,7 @@ func (u *Upstash) JsonClear(ctx context.Context, key string, path ...string) (in
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("JSON.CLEAR", res)
 }
 
 // JsonForget is an alias for JsonDel.
@@ -116,7 +165,7 @@ func (u *Upstash) JsonForget(ctx context.Context, key string, path ...string) (i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("JSON.FORGET", res)
 }
 
 // JsonMerge merges a JSON value into a key at a given path.
@@ -125,7 +174,7 @@ func (u *Upstash) JsonMerge(ctx context.Context, key, path string, value any) (s
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("JSON.MERGE", res)
 }
 
 // JsonNumIncrBy increments a number in a JSON document by a given value.
@@ -146,10 +195,13 @@ func (u *Upstash) JsonObjKeys(ctx context.Context, key, path string) ([]string,
 	if res == nil {
 		return nil, nil
 	}
-	list := res.([]any)
+	list, err := asList("JSON.OBJKEYS", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -160,14 +212,17 @@ func (u *Upstash) JsonObjLen(ctx context.Context, key, path string) ([]int, erro
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
-		}
+	return u.parseIntSlice("JSON.OBJLEN", res)
+}
+
+// JsonObjLenPtr is like JsonObjLen but returns nil for each path match that is not an object,
+// distinguishing it from an empty object.
+func (u *Upstash) JsonObjLenPtr(ctx context.Context, key, path string) ([]*int, error) {
+	res, err := u.Send(ctx, "JSON.OBJLEN", key, path)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return u.parseIntPtrSlice("JSON.OBJLEN", res)
 }
 
 // JsonStrAppend appends a string to the JSON string value at path in key.
@@ -176,14 +231,7 @@ func (u *Upstash) JsonStrAppend(ctx context.Context, key, path, value string) ([
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
-		}
-	}
-	return result, nil
+	return u.parseIntSlice("JSON.STRAPPEND", res)
 }
 
 // JsonStrLen returns the length of the JSON string value at path in key.
@@ -192,14 +240,17 @@ func (u *Upstash) JsonStrLen(ctx context.Context, key, path string) ([]int, erro
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
-		}
+	return u.parseIntSlice("JSON.STRLEN", res)
+}
+
+// JsonStrLenPtr is like JsonStrLen but returns nil for each path match that is not a string,
+// distinguishing it from an empty string.
+func (u *Upstash) JsonStrLenPtr(ctx context.Context, key, path string) ([]*int, error) {
+	res, err := u.Send(ctx, "JSON.STRLEN", key, path)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return u.parseIntPtrSlice("JSON.STRLEN", res)
 }
 
 // JsonToggle toggles a boolean value at path in key.
@@ -217,7 +268,7 @@ func (u *Upstash) JsonArrIndex(ctx context.Context, key, path string, value any,
 	if err != nil {
 		return nil, err
 	}
-	return u.parseIntSlice(res), nil
+	return u.parseIntSlice("JSON.ARRINDEX", res)
 }
 
 // JsonArrInsert inserts JSON values into an array at a given index.
@@ -229,7 +280,7 @@ func (u *Upstash) JsonArrInsert(ctx context.Context, key, path string, index int
 	if err != nil {
 		return nil, err
 	}
-	return u.parseIntSlice(res), nil
+	return u.parseIntSlice("JSON.ARRINSERT", res)
 }
 
 // JsonArrPop removes and returns an element from an array.
@@ -242,7 +293,7 @@ func (u *Upstash) JsonArrPop(ctx context.Context, key, path string, index ...int
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("JSON.ARRPOP", res)
 }
 
 // JsonArrTrim trims an array to contain only the specified range of elements.
@@ -251,7 +302,7 @@ func (u *Upstash) JsonArrTrim(ctx context.Context, key, path string, start, stop
 	if err != nil {
 		return nil, err
 	}
-	return u.parseIntSlice(res), nil
+	return u.parseIntSlice("JSON.ARRTRIM", res)
 }
 
 // JsonNumMultBy multiplies a number in a JSON document by a given value.
@@ -263,13 +314,99 @@ func (u *Upstash) JsonNumMultBy(ctx context.Context, key, path string, value flo
 	return fmt.Sprint(res), nil
 }
 
-func (u *Upstash) parseIntSlice(res any) []int {
-	list := res.([]any)
+// JsonSetStruct marshals v with encoding/json and sets the result at path in key.
+// Unlike JsonSet it does not depend on how the client encodes non-string arguments.
+func (u *Upstash) JsonSetStruct(ctx context.Context, key, path string, v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal %T: %w", v, err)
+	}
+	return u.JsonSet(ctx, key, path, string(b))
+}
+
+// JsonGetInto reads the value at path in key and unmarshals it into dest.
+// JSONPath results (paths starting with "$") come wrapped in an array of matches: a single
+// match is unwrapped, while multiple matches are unmarshaled as an array, so dest must be a slice.
+// Legacy paths return the value itself, which is unmarshaled as is.
+// It returns ErrNil if the key does not exist or the path matched nothing.
+func (u *Upstash) JsonGetInto(ctx context.Context, key, path string, dest any) error {
+	res, err := u.JsonGet(ctx, key, path)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return ErrNil
+	}
+	s, err := asString("JSON.GET", res)
+	if err != nil {
+		return err
+	}
+	data := []byte(s)
+	if strings.HasPrefix(path, "$") {
+		var matches []json.RawMessage
+		if err := json.Unmarshal(data, &matches); err != nil {
+			return fmt.Errorf("unable to decode json: %w", err)
+		}
+		switch len(matches) {
+		case 0:
+			return ErrNil
+		case 1:
+			data = matches[0]
+		}
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("unable to decode json into %T: %w", dest, err)
+	}
+	return nil
+}
+
+// PutDocument marshals v to JSON and stores it as the root document at key.
+func (u *Upstash) PutDocument(ctx context.Context, key string, v any) error {
+	_, err := u.JsonSetStruct(ctx, key, "$", v)
+	return err
+}
+
+// GetDocument reads the root document at key and unmarshals it into dest.
+// It returns an error if the key does not exist.
+func (u *Upstash) GetDocument(ctx context.Context, key string, dest any) error {
+	err := u.JsonGetInto(ctx, key, "$", dest)
+	if errors.Is(err, ErrNil) {
+		return fmt.Errorf("document %s not found", key)
+	}
+	return err
+}
+
+// parseIntSlice converts a reply to command with one integer per path match, turning null entries into 0.
+func (u *Upstash) parseIntSlice(command string, res any) ([]int, error) {
+	list, err := asList(command, res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]int, len(list))
 	for i, v := range list {
-		if v != nil {
-			result[i] = int(v.(float64))
+		if result[i], err = asInt(command, v); err != nil {
+			return nil, err
 		}
 	}
-	return result
+	return result, nil
+}
+
+// parseIntPtrSlice converts a reply with one entry per path match, keeping null entries as nil.
+func (u *Upstash) parseIntPtrSlice(command string, res any) ([]*int, error) {
+	list, err := asList(command, res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*int, len(list))
+	for i, v := range list {
+		if v == nil {
+			continue
+		}
+		n, err := asInt(command, v)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &n
+	}
+	return result, nil
 }
@@ -2,6 +2,8 @@ package upstash
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
 // LPush inserts all the specified values at the head of the list stored at key.
@@ -15,7 +17,7 @@ func (u *Upstash) LPush(ctx context.Context, key string, values ...string) (int,
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("LPUSH", res)
 }
 
 // RPush inserts all the specified values at the tail of the list stored at key.
@@ -29,7 +31,35 @@ func (u *Upstash) RPush(ctx context.Context, key string, values ...string) (int,
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("RPUSH", res)
+}
+
+// PushConfirmed appends values to the tail of the list and returns the list length reported by a
+// pipelined LLEN, letting queue producers verify the push against their expectations.
+func (u *Upstash) PushConfirmed(ctx context.Context, key string, values ...string) (int, error) {
+	args := make([]any, 0, 1+len(values))
+	args = append(args, key)
+	for _, v := range values {
+		args = append(args, v)
+	}
+	pipe := u.Pipeline()
+	pipe.Push("RPUSH", args...)
+	pipe.Push("LLEN", key)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	if _, err := pipelineResult(res[0]); err != nil {
+		return 0, err
+	}
+	length, err := pipelineResult(res[1])
+	if err != nil {
+		return 0, err
+	}
+	return asInt("LLEN", length)
 }
 
 // LPop removes and returns the first element of the list stored at key.
@@ -41,7 +71,7 @@ func (u *Upstash) LPop(ctx context.Context, key string) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("LPOP", res)
 }
 
 // RPop removes and returns the last element of the list stored at key.
@@ -53,7 +83,7 @@ func (u *Upstash) RPop(ctx context.Context, key string) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("RPOP", res)
 }
 
 // LLen returns the length of the list stored at key.
@@ -62,7 +92,40 @@ func (u *Upstash) LLen(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("LLEN", res)
+}
+
+// LLenInt64 is like LLen but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) LLenInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "LLEN", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("LLEN", res)
+}
+
+// ListEncoding returns the internal encoding of the list stored at key via OBJECT ENCODING:
+// "listpack" while the list is small, "quicklist" once it has outgrown the list-max-listpack-size
+// threshold. Servers older than Redis 7 report "ziplist" instead of "listpack".
+// It returns an empty string if key does not exist.
+func (u *Upstash) ListEncoding(ctx context.Context, key string) (string, error) {
+	return u.ObjectEncoding(ctx, key)
+}
+
+// ListIsQuicklist reports whether the list stored at key has been converted from the compact
+// listpack encoding to a quicklist, which uses noticeably more memory per element.
+// Lists that keep converting are worth splitting or trimming, or the threshold worth raising.
+func (u *Upstash) ListIsQuicklist(ctx context.Context, key string) (bool, error) {
+	encoding, err := u.ListEncoding(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return isQuicklistEncoding(encoding), nil
+}
+
+// isQuicklistEncoding reports whether encoding is one of the non-compact list encodings.
+func isQuicklistEncoding(encoding string) bool {
+	return encoding == "quicklist" || encoding == "linkedlist"
 }
 
 // LIndex returns the element at index index in the list stored at key.
@@ -74,7 +137,7 @@ func (u *Upstash) LIndex(ctx context.Context, key string, index int) (string, er
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("LINDEX", res)
 }
 
 // LInsert inserts element in the list stored at key either before or after the reference value pivot.
@@ -83,7 +146,7 @@ func (u *Upstash) LInsert(ctx context.Context, key, op, pivot, element string) (
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("LINSERT", res)
 }
 
 // LMove atomically returns and removes the first/last element of the list stored at source,
@@ -96,7 +159,7 @@ func (u *Upstash) LMove(ctx context.Context, source, destination, srcPos, destPo
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("LMOVE", res)
 }
 
 // LPos returns the index of matching elements inside a list.
@@ -108,7 +171,7 @@ func (u *Upstash) LPos(ctx context.Context, key, element string) (int, error) {
 	if res == nil {
 		return -1, nil
 	}
-	return int(res.(float64)), nil
+	return asInt("LPOS", res)
 }
 
 // LPushX inserts value at the head of the list stored at key, only if key already exists and holds a list.
@@ -122,19 +185,28 @@ func (u *Upstash) LPushX(ctx context.Context, key string, values ...string) (int
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("LPUSHX", res)
 }
 
 // LRange returns the specified elements of the list stored at key.
 func (u *Upstash) LRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	if err := u.checkRangeSize(ctx, "LRANGE", "LLEN", key, start, stop); err != nil {
+		return nil, err
+	}
 	res, err := u.Send(ctx, "LRANGE", key, start, stop)
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("LRANGE", res)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCollectionSize("LRANGE", len(list)); err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -145,7 +217,7 @@ func (u *Upstash) LRem(ctx context.Context, key string, count int, value string)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("LREM", res)
 }
 
 // LSet sets the list element at index to value.
@@ -154,7 +226,7 @@ func (u *Upstash) LSet(ctx context.Context, key string, index int, value string)
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("LSET", res)
 }
 
 // LTrim trims an existing list so that it will contain only the specified range of elements specified.
@@ -163,7 +235,7 @@ func (u *Upstash) LTrim(ctx context.Context, key string, start, stop int) (strin
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("LTRIM", res)
 }
 
 // RPopLPush atomically returns and removes the last element of the list stored at source,
@@ -176,7 +248,7 @@ func (u *Upstash) RPopLPush(ctx context.Context, source, destination string) (st
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("RPOPLPUSH", res)
 }
 
 // BLPop is a blocking list pop primitive.
@@ -193,10 +265,13 @@ func (u *Upstash) BLPop(ctx context.Context, timeout int64, keys ...string) ([]s
 	if res == nil {
 		return nil, nil
 	}
-	list := res.([]any)
+	list, err := asList("BLPOP", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -215,10 +290,13 @@ func (u *Upstash) BRPop(ctx context.Context, timeout int64, keys ...string) ([]s
 	if res == nil {
 		return nil, nil
 	}
-	list := res.([]any)
+	list, err := asList("BRPOP", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -234,7 +312,7 @@ func (u *Upstash) RPushX(ctx context.Context, key string, values ...string) (int
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("RPUSHX", res)
 }
 
 // LCS returns the longest common subsequence of two strings.
@@ -272,3 +350,93 @@ func (u *Upstash) BLMPop(ctx context.Context, timeout int64, numKeys int, keys [
 	}
 	return u.Send(ctx, "BLMPOP", args...)
 }
+
+// BLPopOK is like BLPop but reports a timeout explicitly.
+// ok is false when no element became available before the timeout expired.
+func (u *Upstash) BLPopOK(ctx context.Context, timeout int64, keys ...string) (key, value string, ok bool, err error) {
+	res, err := u.BLPop(ctx, timeout, keys...)
+	if err != nil || len(res) < 2 {
+		return "", "", false, err
+	}
+	return res[0], res[1], true, nil
+}
+
+// BRPopOK is like BRPop but reports a timeout explicitly.
+// ok is false when no element became available before the timeout expired.
+func (u *Upstash) BRPopOK(ctx context.Context, timeout int64, keys ...string) (key, value string, ok bool, err error) {
+	res, err := u.BRPop(ctx, timeout, keys...)
+	if err != nil || len(res) < 2 {
+		return "", "", false, err
+	}
+	return res[0], res[1], true, nil
+}
+
+// ConsumeList runs a worker loop that pops values from the head of the list with BLPOP and passes
+// each one to handler, until ctx is cancelled or handler returns an error.
+// A value is removed from the list before handler runs, so it is lost if handler fails;
+// use ConsumeListReliable when values must survive a failed handler.
+// timeout bounds each BLPOP call and is rounded down to whole seconds, with a minimum of one second.
+// It returns ctx.Err() once ctx is cancelled.
+func (u *Upstash) ConsumeList(ctx context.Context, key string, handler func(value string) error, timeout time.Duration) error {
+	secs := blockingSeconds(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, value, ok, err := u.BLPopOK(ctx, secs, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := handler(value); err != nil {
+			return err
+		}
+	}
+}
+
+// ConsumeListReliable is like ConsumeList, but atomically moves each value to the processing list
+// with BLMOVE before calling handler and removes it from there once handler succeeds.
+// If handler fails the value stays in the processing list so it can be recovered.
+func (u *Upstash) ConsumeListReliable(ctx context.Context, key, processing string, handler func(value string) error, timeout time.Duration) error {
+	secs := blockingSeconds(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := u.Send(ctx, "BLMOVE", key, processing, "LEFT", "RIGHT", secs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if res == nil {
+			continue
+		}
+		value, err := asString("BLMOVE", res)
+		if err != nil {
+			return err
+		}
+		if err := handler(value); err != nil {
+			return err
+		}
+		if _, err := u.LRem(ctx, processing, 1, value); err != nil {
+			return err
+		}
+	}
+}
+
+// blockingSeconds converts timeout to the whole-second timeout of a blocking command.
+// Zero would block forever, so it is raised to one second.
+func blockingSeconds(timeout time.Duration) int64 {
+	secs := int64(timeout / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
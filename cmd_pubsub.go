@@ -3,8 +3,12 @@ package upstash
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/claywarren/upstash-go/internal/rest"
 )
@@ -15,23 +19,70 @@ func (u *Upstash) Publish(ctx context.Context, channel, message string) (int, er
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("PUBLISH", res)
 }
 
 // Subscribe subscribes to a channel and returns a channel of messages.
+// It waits for the server to confirm the subscription, so a failed subscription is reported as an error.
+// The subscription ends when ctx is cancelled or CloseAllSubscriptions is called.
 func (u *Upstash) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	stream, err := u.client.Stream(ctx, rest.Request{
 		Path: []string{"subscribe", channel},
 	})
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
+	scanner := bufio.NewScanner(stream)
+	if err := awaitSubscribeConfirmation(scanner, channel); err != nil {
+		_ = stream.Close()
+		cancel()
+		return nil, err
+	}
+
+	id := u.subs.add(channel, cancel)
 	out := make(chan string)
-	go u.streamReader(ctx, stream, out)
+	go func() {
+		defer cancel()
+		defer u.subs.remove(id)
+		u.streamReader(ctx, stream, scanner, out)
+	}()
 	return out, nil
 }
 
+// awaitSubscribeConfirmation consumes the stream until the server confirms the subscription.
+func awaitSubscribeConfirmation(scanner *bufio.Scanner, channel string) error {
+	for scanner.Scan() {
+		msg, ok := sseData(scanner.Text())
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(msg, "subscribe,"):
+			return nil
+		case strings.HasPrefix(msg, "error"):
+			return fmt.Errorf("subscribe %s: %s", channel, msg)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("subscribe %s: %w", channel, err)
+	}
+	return fmt.Errorf("subscribe %s: stream closed before confirmation", channel)
+}
+
+// ActiveSubscriptions returns the channels of all subscriptions opened by Subscribe that are still active.
+func (u *Upstash) ActiveSubscriptions() []string {
+	return u.subs.channels()
+}
+
+// CloseAllSubscriptions cancels all subscriptions opened by Subscribe.
+// Their message channels are closed once the underlying streams have shut down.
+func (u *Upstash) CloseAllSubscriptions() {
+	u.subs.closeAll()
+}
+
 // Monitor monitors all commands hitting the database in real-time.
 func (u *Upstash) Monitor(ctx context.Context) (<-chan string, error) {
 	stream, err := u.client.Stream(ctx, rest.Request{
@@ -42,7 +93,7 @@ func (u *Upstash) Monitor(ctx context.Context) (<-chan string, error) {
 	}
 
 	out := make(chan string)
-	go u.streamReader(ctx, stream, out)
+	go u.streamReader(ctx, stream, bufio.NewScanner(stream), out)
 	return out, nil
 }
 
@@ -54,6 +105,61 @@ func (u *Upstash) PubSub(ctx context.Context, subcommand string, args ...any) (a
 	return u.Send(ctx, "PUBSUB", fullArgs...)
 }
 
+// PubSubChannels lists the currently active channels, optionally filtered by a glob-style pattern.
+func (u *Upstash) PubSubChannels(ctx context.Context, pattern string) ([]string, error) {
+	args := make([]any, 0, 1)
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	res, err := u.PubSub(ctx, "CHANNELS", args...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("PUBSUB CHANNELS", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// PubSubNumSub returns the number of subscribers for each of the given channels.
+func (u *Upstash) PubSubNumSub(ctx context.Context, channels ...string) (map[string]int, error) {
+	args := make([]any, 0, len(channels))
+	for _, c := range channels {
+		args = append(args, c)
+	}
+	res, err := u.PubSub(ctx, "NUMSUB", args...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("PUBSUB NUMSUB", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		n, err := asInt("PUBSUB NUMSUB", list[i+1])
+		if err != nil {
+			return nil, err
+		}
+		result[toString(list[i])] = n
+	}
+	return result, nil
+}
+
+// PubSubNumPat returns the number of unique patterns that are subscribed to.
+func (u *Upstash) PubSubNumPat(ctx context.Context) (int, error) {
+	res, err := u.PubSub(ctx, "NUMPAT")
+	if err != nil {
+		return 0, err
+	}
+	return asInt("PUBSUB NUMPAT", res)
+}
+
 // Unsubscribe unsubscribes the client from the given channels, or from all of them if none is given.
 // Note: In REST API context, this might not have the same effect as in TCP, but added for parity.
 func (u *Upstash) Unsubscribe(ctx context.Context, channels ...string) (any, error) {
@@ -64,21 +170,14 @@ func (u *Upstash) Unsubscribe(ctx context.Context, channels ...string) (any, err
 	return u.Send(ctx, "UNSUBSCRIBE", args...)
 }
 
-func (u *Upstash) streamReader(ctx context.Context, stream io.ReadCloser, out chan<- string) {
+func (u *Upstash) streamReader(ctx context.Context, stream io.ReadCloser, scanner *bufio.Scanner, out chan<- string) {
 	defer func() {
 		_ = stream.Close()
 	}()
 	defer close(out)
 
-	scanner := bufio.NewScanner(stream)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "data: ") {
-			msg := strings.TrimPrefix(line, "data: ")
-			// Upstash might wrap the data in quotes if it's a string from JSON
-			if strings.HasPrefix(msg, "\"") && strings.HasSuffix(msg, "\"") && len(msg) >= 2 {
-				msg = msg[1 : len(msg)-1]
-			}
+		if msg, ok := sseData(scanner.Text()); ok {
 			select {
 			case out <- msg:
 			case <-ctx.Done():
@@ -93,3 +192,81 @@ func (u *Upstash) streamReader(ctx context.Context, stream io.ReadCloser, out ch
 		}
 	}
 }
+
+// sseData returns the payload of an SSE data line.
+func sseData(line string) (string, bool) {
+	if !strings.HasPrefix(line, "data: ") {
+		return "", false
+	}
+	msg := strings.TrimPrefix(line, "data: ")
+	// Upstash might send the data as a JSON string; decode it, escapes included.
+	// Payloads that merely look quoted but are not valid JSON strings are kept raw.
+	if len(msg) >= 2 && strings.HasPrefix(msg, "\"") && strings.HasSuffix(msg, "\"") {
+		var decoded string
+		if err := json.Unmarshal([]byte(msg), &decoded); err == nil {
+			msg = decoded
+		}
+	}
+	return msg, true
+}
+
+type subscription struct {
+	channel string
+	cancel  context.CancelFunc
+}
+
+// subscriptionRegistry tracks the active subscriptions of a client.
+// A nil registry tracks nothing, so a zero Upstash value remains usable.
+type subscriptionRegistry struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]subscription
+}
+
+func (r *subscriptionRegistry) add(channel string, cancel context.CancelFunc) int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.subs == nil {
+		r.subs = make(map[int]subscription)
+	}
+	r.nextID++
+	r.subs[r.nextID] = subscription{channel: channel, cancel: cancel}
+	return r.nextID
+}
+
+func (r *subscriptionRegistry) remove(id int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subs, id)
+}
+
+func (r *subscriptionRegistry) channels() []string {
+	if r == nil {
+		return []string{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]string, 0, len(r.subs))
+	for _, s := range r.subs {
+		result = append(result, s.channel)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (r *subscriptionRegistry) closeAll() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.subs {
+		s.cancel()
+	}
+}
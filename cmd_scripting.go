@@ -2,6 +2,11 @@ package upstash
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 // Eval executes a Lua script server side.
@@ -15,6 +20,23 @@ func (u *Upstash) Eval(ctx context.Context, script string, keys []string, args .
 	return u.Send(ctx, "EVAL", cmdArgs...)
 }
 
+// EvalInto executes a Lua script server side and decodes the result into dest.
+// Lua tables are returned as arrays, so dest is typically a pointer to a slice.
+func (u *Upstash) EvalInto(ctx context.Context, dest any, script string, keys []string, args ...any) error {
+	res, err := u.Eval(ctx, script, keys, args...)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("unable to marshal eval result: %w", err)
+	}
+	if err := json.Unmarshal(b, dest); err != nil {
+		return fmt.Errorf("unable to decode eval result into %T: %w", dest, err)
+	}
+	return nil
+}
+
 // EvalSha executes a Lua script server side by its SHA1 digest.
 func (u *Upstash) EvalSha(ctx context.Context, sha1 string, keys []string, args ...any) (any, error) {
 	cmdArgs := make([]any, 0, 2+len(keys)+len(args))
@@ -26,11 +48,33 @@ func (u *Upstash) EvalSha(ctx context.Context, sha1 string, keys []string, args
 	return u.Send(ctx, "EVALSHA", cmdArgs...)
 }
 
+// EvalCached executes a Lua script server side by its SHA1 digest,
+// falling back to EVAL if the script is not yet in the scripts cache.
+// This avoids sending the full script body on every call.
+func (u *Upstash) EvalCached(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	sum := sha1.Sum([]byte(script))
+	res, err := u.EvalSha(ctx, hex.EncodeToString(sum[:]), keys, args...)
+	if err != nil && strings.Contains(err.Error(), "NOSCRIPT") {
+		return u.Eval(ctx, script, keys, args...)
+	}
+	return res, err
+}
+
+// ScriptKill kills the currently executing Lua script, provided it has not performed any write yet.
+// It returns an error wrapping ErrNotBusy if no script is running.
+func (u *Upstash) ScriptKill(ctx context.Context) (string, error) {
+	res, err := u.Send(ctx, "SCRIPT", "KILL")
+	if err != nil {
+		return "", err
+	}
+	return asString("SCRIPT KILL", res)
+}
+
 // ScriptLoad loads a Lua script into the scripts cache.
 func (u *Upstash) ScriptLoad(ctx context.Context, script string) (string, error) {
 	res, err := u.Send(ctx, "SCRIPT", "LOAD", script)
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("SCRIPT LOAD", res)
 }
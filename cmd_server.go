@@ -10,7 +10,16 @@ func (u *Upstash) DBSize(ctx context.Context) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("DBSIZE", res)
+}
+
+// DBSizeInt64 is like DBSize but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) DBSizeInt64(ctx context.Context) (int64, error) {
+	res, err := u.Send(ctx, "DBSIZE")
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("DBSIZE", res)
 }
 
 // Info returns information and statistics about the server.
@@ -23,7 +32,7 @@ func (u *Upstash) Info(ctx context.Context, section ...string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("INFO", res)
 }
 
 // Time returns the current server time.
@@ -32,10 +41,13 @@ func (u *Upstash) Time(ctx context.Context) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("TIME", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -46,7 +58,7 @@ func (u *Upstash) Role(ctx context.Context) ([]any, error) {
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("ROLE", res)
 }
 
 // LastSave returns the Unix time stamp of the last successful save to disk.
@@ -55,7 +67,7 @@ func (u *Upstash) LastSave(ctx context.Context) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int64(res.(float64)), nil
+	return asInt64("LASTSAVE", res)
 }
 
 // Command returns information about all Redis commands.
@@ -64,5 +76,80 @@ func (u *Upstash) Command(ctx context.Context) ([]any, error) {
 	if err != nil {
 		return nil, err
 	}
-	return res.([]any), nil
+	return asList("COMMAND", res)
+}
+
+// CommandSupported reports whether the server knows command, using COMMAND INFO.
+// Commands that are unknown or disabled, as some are on Upstash, are reported as unsupported.
+func (u *Upstash) CommandSupported(ctx context.Context, command string) (bool, error) {
+	res, err := u.Send(ctx, "COMMAND", "INFO", command)
+	if err != nil {
+		return false, err
+	}
+	list, err := asList("COMMAND INFO", res)
+	if err != nil {
+		return false, err
+	}
+	return len(list) > 0 && list[0] != nil, nil
+}
+
+// LatencyLatest returns the latest latency spike recorded for each event.
+// Databases that restrict the LATENCY commands return an error wrapping ErrNoPerm.
+func (u *Upstash) LatencyLatest(ctx context.Context) ([]LatencyEvent, error) {
+	res, err := u.Send(ctx, "LATENCY", "LATEST")
+	if err != nil {
+		return nil, err
+	}
+	list, _ := res.([]any)
+	result := make([]LatencyEvent, 0, len(list))
+	for _, e := range list {
+		fields, _ := e.([]any)
+		if len(fields) < 4 {
+			continue
+		}
+		result = append(result, LatencyEvent{
+			Name:      toString(fields[0]),
+			Timestamp: toInt64(fields[1]),
+			Latest:    toInt64(fields[2]),
+			Max:       toInt64(fields[3]),
+		})
+	}
+	return result, nil
+}
+
+// LatencyHistory returns the latency spikes recorded for event, oldest first.
+// Databases that restrict the LATENCY commands return an error wrapping ErrNoPerm.
+func (u *Upstash) LatencyHistory(ctx context.Context, event string) ([]LatencySample, error) {
+	res, err := u.Send(ctx, "LATENCY", "HISTORY", event)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := res.([]any)
+	result := make([]LatencySample, 0, len(list))
+	for _, s := range list {
+		fields, _ := s.([]any)
+		if len(fields) < 2 {
+			continue
+		}
+		result = append(result, LatencySample{
+			Timestamp: toInt64(fields[0]),
+			Latency:   toInt64(fields[1]),
+		})
+	}
+	return result, nil
+}
+
+// LatencyReset resets the latency data of the given events, or of all events if none are given.
+// It returns the number of event time series that were reset.
+func (u *Upstash) LatencyReset(ctx context.Context, events ...string) (int, error) {
+	args := make([]any, 0, 1+len(events))
+	args = append(args, "RESET")
+	for _, e := range events {
+		args = append(args, e)
+	}
+	res, err := u.Send(ctx, "LATENCY", args...)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("LATENCY", res)
 }
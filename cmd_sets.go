@@ -2,20 +2,29 @@ package upstash
 
 import (
 	"context"
+	"fmt"
 )
 
 // SAdd adds one or more members to a set.
+// Members beyond the SADD batch size (see Options.MaxBatchSize) are sent in chunks.
 func (u *Upstash) SAdd(ctx context.Context, key string, members ...string) (int, error) {
-	args := make([]any, 0, 1+len(members))
-	args = append(args, key)
-	for _, m := range members {
-		args = append(args, m)
+	items := make([][]any, len(members))
+	for i, m := range members {
+		items[i] = []any{m}
 	}
-	res, err := u.Send(ctx, "SADD", args...)
+	res, err := u.sendBatched(ctx, "SADD", []any{key}, items)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	added := 0
+	for _, r := range res {
+		n, err := asInt("SADD", r)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	return added, nil
 }
 
 // SRem removes one or more members from a set.
@@ -29,7 +38,7 @@ func (u *Upstash) SRem(ctx context.Context, key string, members ...string) (int,
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SREM", res)
 }
 
 // SIsMember returns if member is a member of the set stored at key.
@@ -38,7 +47,13 @@ func (u *Upstash) SIsMember(ctx context.Context, key, member string) (int, error
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SISMEMBER", res)
+}
+
+// SIsMemberBool reports whether member is a member of the set stored at key.
+func (u *Upstash) SIsMemberBool(ctx context.Context, key, member string) (bool, error) {
+	res, err := u.SIsMember(ctx, key, member)
+	return res == 1, err
 }
 
 // SMembers returns all the members of the set value stored at key.
@@ -47,21 +62,80 @@ func (u *Upstash) SMembers(ctx context.Context, key string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("SMEMBERS", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// SMembersSet returns all the members of the set value stored at key as a Go set.
+func (u *Upstash) SMembersSet(ctx context.Context, key string) (map[string]struct{}, error) {
+	members, err := u.SMembers(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		result[m] = struct{}{}
 	}
 	return result, nil
 }
 
+// SMembersAuto returns all the members of the set value stored at key,
+// using SMEMBERS for sets of at most threshold members and paging through SSCAN for larger sets,
+// so a single request never has to return a huge reply. The SSCAN path is not atomic.
+func (u *Upstash) SMembersAuto(ctx context.Context, key string, threshold int) ([]string, error) {
+	card, err := u.SCard(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if card <= threshold {
+		return u.SMembers(ctx, key)
+	}
+
+	// SSCAN may return the same member more than once.
+	seen := make(map[string]struct{}, card)
+	result := make([]string, 0, card)
+	cursor := "0"
+	for {
+		page, err := u.SScan(ctx, key, cursor, ScanOptions{Count: threshold})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page.Items {
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				result = append(result, m)
+			}
+		}
+		cursor = page.Cursor
+		if cursor == "0" {
+			return result, nil
+		}
+	}
+}
+
 // SCard returns the set cardinality (number of elements) of the set stored at key.
 func (u *Upstash) SCard(ctx context.Context, key string) (int, error) {
 	res, err := u.Send(ctx, "SCARD", key)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SCARD", res)
+}
+
+// SCardInt64 is like SCard but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) SCardInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "SCARD", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("SCARD", res)
 }
 
 // SScan iterates over members of a set.
@@ -79,10 +153,13 @@ func (u *Upstash) SDiff(ctx context.Context, keys ...string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("SDIFF", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -98,7 +175,13 @@ func (u *Upstash) SDiffStore(ctx context.Context, destination string, keys ...st
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SDIFFSTORE", res)
+}
+
+// SDiffStoreAndGet is like SDiffStore but also returns the members of the resulting set,
+// read with a pipelined SMEMBERS in the same round trip.
+func (u *Upstash) SDiffStoreAndGet(ctx context.Context, destination string, keys ...string) (int, []string, error) {
+	return u.storeAndGet(ctx, "SDIFFSTORE", destination, keys)
 }
 
 // SInter returns the members of the set resulting from the intersection of all the given sets.
@@ -111,10 +194,13 @@ func (u *Upstash) SInter(ctx context.Context, keys ...string) ([]string, error)
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("SINTER", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -130,7 +216,13 @@ func (u *Upstash) SInterStore(ctx context.Context, destination string, keys ...s
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SINTERSTORE", res)
+}
+
+// SInterStoreAndGet is like SInterStore but also returns the members of the resulting set,
+// read with a pipelined SMEMBERS in the same round trip.
+func (u *Upstash) SInterStoreAndGet(ctx context.Context, destination string, keys ...string) (int, []string, error) {
+	return u.storeAndGet(ctx, "SINTERSTORE", destination, keys)
 }
 
 // SMove moves member from the set at source to the set at destination.
@@ -139,7 +231,7 @@ func (u *Upstash) SMove(ctx context.Context, source, destination, member string)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SMOVE", res)
 }
 
 // SPop removes and returns one or more random members from the set value store at key.
@@ -147,6 +239,9 @@ func (u *Upstash) SPop(ctx context.Context, key string, count ...int) (any, erro
 	args := make([]any, 0, 1+len(count))
 	args = append(args, key)
 	if len(count) > 0 {
+		if err := u.checkCollectionSize("SPOP", count[0]); err != nil {
+			return nil, err
+		}
 		args = append(args, count[0])
 	}
 	return u.Send(ctx, "SPOP", args...)
@@ -157,6 +252,14 @@ func (u *Upstash) SRandMember(ctx context.Context, key string, count ...int) (an
 	args := make([]any, 0, 1+len(count))
 	args = append(args, key)
 	if len(count) > 0 {
+		// A negative count may return the same member multiple times, -count members in total.
+		n := count[0]
+		if n < 0 {
+			n = -n
+		}
+		if err := u.checkCollectionSize("SRANDMEMBER", n); err != nil {
+			return nil, err
+		}
 		args = append(args, count[0])
 	}
 	return u.Send(ctx, "SRANDMEMBER", args...)
@@ -172,10 +275,13 @@ func (u *Upstash) SUnion(ctx context.Context, keys ...string) ([]string, error)
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("SUNION", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -191,7 +297,13 @@ func (u *Upstash) SUnionStore(ctx context.Context, destination string, keys ...s
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SUNIONSTORE", res)
+}
+
+// SUnionStoreAndGet is like SUnionStore but also returns the members of the resulting set,
+// read with a pipelined SMEMBERS in the same round trip.
+func (u *Upstash) SUnionStoreAndGet(ctx context.Context, destination string, keys ...string) (int, []string, error) {
+	return u.storeAndGet(ctx, "SUNIONSTORE", destination, keys)
 }
 
 // SMIsMember returns whether the members are members of the set stored at key.
@@ -205,12 +317,7 @@ func (u *Upstash) SMIsMember(ctx context.Context, key string, members ...string)
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
-	result := make([]int, len(list))
-	for i, v := range list {
-		result[i] = int(v.(float64))
-	}
-	return result, nil
+	return u.parseIntSlice("SMISMEMBER", res)
 }
 
 // SInterCard returns the cardinality of the set resulting from the intersection of all the given sets.
@@ -227,5 +334,45 @@ func (u *Upstash) SInterCard(ctx context.Context, keys []string, limit ...int) (
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SINTERCARD", res)
+}
+
+// storeAndGet pipelines the set operation command storing into destination with an SMEMBERS of destination.
+func (u *Upstash) storeAndGet(ctx context.Context, command, destination string, keys []string) (int, []string, error) {
+	args := make([]any, 0, 1+len(keys))
+	args = append(args, destination)
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	pipe := u.Pipeline()
+	pipe.Push(command, args...)
+	pipe.Push("SMEMBERS", destination)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(res) != 2 {
+		return 0, nil, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	stored, err := pipelineResult(res[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	count, err := asInt(command, stored)
+	if err != nil {
+		return 0, nil, err
+	}
+	membersRes, err := pipelineResult(res[1])
+	if err != nil {
+		return 0, nil, err
+	}
+	list, err := asList("SMEMBERS", membersRes)
+	if err != nil {
+		return 0, nil, err
+	}
+	members := make([]string, len(list))
+	for i, v := range list {
+		members[i] = toString(v)
+	}
+	return count, members, nil
 }
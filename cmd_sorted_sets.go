@@ -2,16 +2,185 @@ package upstash
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"strconv"
 )
 
 // ZAdd adds all the specified members with the specified scores to the sorted set stored at key.
+// Infinite scores are sent as "+inf" or "-inf"; a NaN score is rejected.
 func (u *Upstash) ZAdd(ctx context.Context, key string, score float64, member string) (int, error) {
-	res, err := u.Send(ctx, "ZADD", key, score, member)
+	s, err := formatScore(score)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	res, err := u.Send(ctx, "ZADD", key, s, member)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("ZADD", res)
+}
+
+// ZAddMulti adds all the given members with their scores to the sorted set stored at key.
+// Members beyond the ZADD batch size (see Options.MaxBatchSize and Options.BatchSizes) are sent in chunks.
+func (u *Upstash) ZAddMulti(ctx context.Context, key string, members []ZMember) (int, error) {
+	items := make([][]any, len(members))
+	for i, m := range members {
+		s, err := formatScore(m.Score)
+		if err != nil {
+			return 0, err
+		}
+		items[i] = []any{s, m.Member}
+	}
+	res, err := u.sendBatched(ctx, "ZADD", []any{key}, items)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, r := range res {
+		n, err := asInt("ZADD", r)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	return added, nil
+}
+
+// ZAddAndCheckEncoding adds members like ZAddMulti and returns the resulting encoding of the
+// sorted set via a pipelined OBJECT ENCODING. A "skiplist" encoding means the set has grown
+// past the compact "listpack" threshold.
+func (u *Upstash) ZAddAndCheckEncoding(ctx context.Context, key string, members []ZMember) (int, string, error) {
+	args, err := zaddArgs(key, members)
+	if err != nil {
+		return 0, "", err
+	}
+	pipe := u.Pipeline()
+	pipe.Push("ZADD", args...)
+	pipe.Push("OBJECT", "ENCODING", key)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(res) != 2 {
+		return 0, "", fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+	added, err := pipelineResult(res[0])
+	if err != nil {
+		return 0, "", err
+	}
+	encoding, err := pipelineResult(res[1])
+	if err != nil {
+		return 0, "", err
+	}
+	n, err := asInt("ZADD", added)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, toString(encoding), nil
+}
+
+// ZAddWithArgs adds members to the sorted set stored at key with the flags set in args.
+// It returns the number of added members, or of changed members with CH. With INCR it instead
+// returns the new score of the member, or ErrNil if a condition flag prevented the increment.
+func (u *Upstash) ZAddWithArgs(ctx context.Context, key string, args ZAddArgs) (float64, error) {
+	fullArgs := zaddConditions([]any{key}, ZAddOptions{NX: args.NX, XX: args.XX, GT: args.GT, LT: args.LT})
+	if args.CH {
+		fullArgs = append(fullArgs, "CH")
+	}
+	if args.INCR {
+		fullArgs = append(fullArgs, "INCR")
+	}
+	for _, m := range args.Members {
+		s, err := formatScore(m.Score)
+		if err != nil {
+			return 0, err
+		}
+		fullArgs = append(fullArgs, s, m.Member)
+	}
+	res, err := u.Send(ctx, "ZADD", fullArgs...)
+	if err != nil {
+		return 0, err
+	}
+	if args.INCR && res == nil {
+		return 0, ErrNil
+	}
+	return asFloat("ZADD", res)
+}
+
+// ZIncrByIf increments the score of member in the sorted set stored at key by increment, with ZADD INCR,
+// only if the conditions in opts hold; e.g. with GT it applies only while the score keeps growing.
+// It returns the resulting score of the member and whether the increment was applied. When it was skipped,
+// the score is read with a ZSCORE in the same transaction; a member that is missing and was skipped, e.g. with XX,
+// reports a score of 0.
+func (u *Upstash) ZIncrByIf(ctx context.Context, key string, increment float64, member string, opts ZAddOptions) (float64, bool, error) {
+	s, err := formatScore(increment)
+	if err != nil {
+		return 0, false, err
+	}
+	args := zaddConditions([]any{key}, opts)
+	args = append(args, "INCR", s, member)
+	tx := u.Multi()
+	tx.Push("ZADD", args...)
+	tx.Push("ZSCORE", key, member)
+	res, err := tx.Exec(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res) != 2 {
+		return 0, false, fmt.Errorf("unexpected transaction result length: %d", len(res))
+	}
+	incremented, err := pipelineResult(res[0])
+	if err != nil {
+		return 0, false, err
+	}
+	if incremented != nil {
+		score, err := asFloat("ZADD", incremented)
+		if err != nil {
+			return 0, false, err
+		}
+		return score, true, nil
+	}
+	current, err := pipelineResult(res[1])
+	if err != nil {
+		return 0, false, err
+	}
+	score, err := asFloat("ZSCORE", current)
+	if err != nil {
+		return 0, false, err
+	}
+	return score, false, nil
+}
+
+// zaddConditions appends the ZADD condition flags set in opts to args.
+func zaddConditions(args []any, opts ZAddOptions) []any {
+	switch {
+	case opts.NX:
+		args = append(args, "NX")
+	case opts.XX:
+		args = append(args, "XX")
+	}
+	switch {
+	case opts.GT:
+		args = append(args, "GT")
+	case opts.LT:
+		args = append(args, "LT")
+	}
+	return args
+}
+
+// zaddArgs builds the ZADD arguments for key followed by score/member pairs.
+func zaddArgs(key string, members []ZMember) ([]any, error) {
+	args := make([]any, 0, 1+2*len(members))
+	args = append(args, key)
+	for _, m := range members {
+		s, err := formatScore(m.Score)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, s, m.Member)
+	}
+	return args, nil
 }
 
 // ZRem removes the specified members from the sorted set stored at key.
@@ -25,30 +194,147 @@ func (u *Upstash) ZRem(ctx context.Context, key string, members ...string) (int,
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZREM", res)
 }
 
 // ZRange returns the specified range of elements in the sorted set stored at key.
 func (u *Upstash) ZRange(ctx context.Context, key string, start, stop int) ([]string, error) {
+	if err := u.checkRangeSize(ctx, "ZRANGE", "ZCARD", key, start, stop); err != nil {
+		return nil, err
+	}
 	res, err := u.Send(ctx, "ZRANGE", key, start, stop)
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZRANGE", res)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCollectionSize("ZRANGE", len(list)); err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// ZRangeWithScores is like ZRange but also returns the score of each member.
+// Scores are parsed whether they arrive as strings, as Redis sends them, or as numbers.
+func (u *Upstash) ZRangeWithScores(ctx context.Context, key string, start, stop int) ([]ZMember, error) {
+	if err := u.checkRangeSize(ctx, "ZRANGE", "ZCARD", key, start, stop); err != nil {
+		return nil, err
+	}
+	res, err := u.Send(ctx, "ZRANGE", key, start, stop, "WITHSCORES")
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("ZRANGE", res)
+	if err != nil {
+		return nil, err
+	}
+	if len(list)%2 != 0 {
+		return nil, fmt.Errorf("zrange: odd number of items: %d", len(list))
+	}
+	if err := u.checkCollectionSize("ZRANGE", len(list)/2); err != nil {
+		return nil, err
+	}
+	result := make([]ZMember, 0, len(list)/2)
+	for i := 0; i < len(list); i += 2 {
+		score, err := asFloat("ZRANGE", list[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("zrange: invalid score for member %v: %w", list[i], err)
+		}
+		result = append(result, ZMember{Score: score, Member: toString(list[i])})
 	}
 	return result, nil
 }
 
+// ZRangeUnified returns the specified range of elements in the sorted set stored at key,
+// using the unified ZRANGE syntax. Depending on the options, start and stop are ranks, scores or lexicographical bounds.
+// With WithScores, the result alternates between members and their scores.
+func (u *Upstash) ZRangeUnified(ctx context.Context, key string, start, stop any, options ZRangeOptions) ([]string, error) {
+	if err := u.checkZRangeUnifiedSize(ctx, key, start, stop, options); err != nil {
+		return nil, err
+	}
+	args := []any{key, start, stop}
+	if options.ByScore {
+		args = append(args, "BYSCORE")
+	} else if options.ByLex {
+		args = append(args, "BYLEX")
+	}
+	if options.Rev {
+		args = append(args, "REV")
+	}
+	if options.Limit != nil {
+		args = append(args, "LIMIT", options.Limit.Offset, options.Limit.Count)
+	}
+	if options.WithScores {
+		args = append(args, "WITHSCORES")
+	}
+	res, err := u.Send(ctx, "ZRANGE", args...)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := res.([]any)
+	n := len(list)
+	if options.WithScores {
+		n /= 2
+	}
+	if err := u.checkCollectionSize("ZRANGE", n); err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = fmt.Sprint(v)
+	}
+	return result, nil
+}
+
+// checkZRangeUnifiedSize applies the MaxCollectionResult cap to a ZRangeUnified call before it is sent:
+// to the LIMIT count if there is one, and otherwise to the ZCOUNT, ZLEXCOUNT or rank width of the range.
+func (u *Upstash) checkZRangeUnifiedSize(ctx context.Context, key string, start, stop any, options ZRangeOptions) error {
+	if options.Limit != nil && options.Limit.Count >= 0 {
+		return u.checkCollectionSize("ZRANGE", options.Limit.Count)
+	}
+	lo, hi := start, stop
+	if options.Rev {
+		lo, hi = stop, start
+	}
+	switch {
+	case options.ByScore:
+		return u.checkCountedSize(ctx, "ZRANGE", "ZCOUNT", key, lo, hi)
+	case options.ByLex:
+		return u.checkCountedSize(ctx, "ZRANGE", "ZLEXCOUNT", key, lo, hi)
+	}
+	startRank, ok := rankIndex(start)
+	if !ok {
+		return nil
+	}
+	stopRank, ok := rankIndex(stop)
+	if !ok {
+		return nil
+	}
+	return u.checkRangeSize(ctx, "ZRANGE", "ZCARD", key, startRank, stopRank)
+}
+
 // ZCard returns the sorted set cardinality (number of elements) of the sorted set stored at key.
 func (u *Upstash) ZCard(ctx context.Context, key string) (int, error) {
 	res, err := u.Send(ctx, "ZCARD", key)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZCARD", res)
+}
+
+// ZCardInt64 is like ZCard but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) ZCardInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "ZCARD", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("ZCARD", res)
 }
 
 // ZScore returns the score of member in the sorted set at key.
@@ -60,7 +346,48 @@ func (u *Upstash) ZScore(ctx context.Context, key, member string) (float64, erro
 	if res == nil {
 		return 0, nil
 	}
-	return strconv.ParseFloat(res.(string), 64)
+	return asFloat("ZSCORE", res)
+}
+
+// ZScoreString returns the score of member in the sorted set at key as the exact string stored by Redis.
+// Use this instead of ZScore when scores may exceed the precision of a float64, such as integers beyond 2^53.
+// ok is false if the member does not exist.
+func (u *Upstash) ZScoreString(ctx context.Context, key, member string) (score string, ok bool, err error) {
+	res, err := u.Send(ctx, "ZSCORE", key, member)
+	if err != nil {
+		return "", false, err
+	}
+	if res == nil {
+		return "", false, nil
+	}
+	return fmt.Sprint(res), true, nil
+}
+
+// ZRangeWithScoresString returns the specified range of elements in the sorted set stored at key,
+// with each score as the exact string stored by Redis.
+func (u *Upstash) ZRangeWithScoresString(ctx context.Context, key string, start, stop int) ([]ZMemberString, error) {
+	if err := u.checkRangeSize(ctx, "ZRANGE", "ZCARD", key, start, stop); err != nil {
+		return nil, err
+	}
+	res, err := u.Send(ctx, "ZRANGE", key, start, stop, "WITHSCORES")
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("ZRANGE", res)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCollectionSize("ZRANGE", len(list)/2); err != nil {
+		return nil, err
+	}
+	result := make([]ZMemberString, 0, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		result = append(result, ZMemberString{
+			Member: fmt.Sprint(list[i]),
+			Score:  fmt.Sprint(list[i+1]),
+		})
+	}
+	return result, nil
 }
 
 // ZScan iterates over members of a sorted set.
@@ -68,13 +395,33 @@ func (u *Upstash) ZScan(ctx context.Context, key, cursor string, options ScanOpt
 	return u.scan(ctx, key, cursor, options, "ZSCAN")
 }
 
+// ZScanPairs is like ZScan but returns the members with their parsed scores.
+func (u *Upstash) ZScanPairs(ctx context.Context, key, cursor string, options ScanOptions) (ZScanResult, error) {
+	page, err := u.ZScan(ctx, key, cursor, options)
+	if err != nil {
+		return ZScanResult{}, err
+	}
+	if len(page.Items)%2 != 0 {
+		return ZScanResult{}, fmt.Errorf("zscan: odd number of items: %d", len(page.Items))
+	}
+	members := make([]ZMember, 0, len(page.Items)/2)
+	for i := 0; i < len(page.Items); i += 2 {
+		score, err := strconv.ParseFloat(page.Items[i+1], 64)
+		if err != nil {
+			return ZScanResult{}, fmt.Errorf("zscan: invalid score for member %s: %w", page.Items[i], err)
+		}
+		members = append(members, ZMember{Score: score, Member: page.Items[i]})
+	}
+	return ZScanResult{Cursor: page.Cursor, Members: members}, nil
+}
+
 // ZCount returns the number of elements in the sorted set at key with a score between min and max.
 func (u *Upstash) ZCount(ctx context.Context, key string, min, max any) (int, error) {
 	res, err := u.Send(ctx, "ZCOUNT", key, min, max)
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZCOUNT", res)
 }
 
 // ZDiff returns the difference between the first sorted set and all successive sorted sets.
@@ -89,21 +436,29 @@ func (u *Upstash) ZDiff(ctx context.Context, numKeys int, keys []string, args ..
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZDIFF", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
 
 // ZIncrBy increments the score of member in the sorted set stored at key by increment.
+// An infinite increment is sent as "+inf" or "-inf"; a NaN increment is rejected.
 func (u *Upstash) ZIncrBy(ctx context.Context, key string, increment float64, member string) (float64, error) {
-	res, err := u.Send(ctx, "ZINCRBY", key, increment, member)
+	inc, err := formatScore(increment)
 	if err != nil {
 		return 0, err
 	}
-	return strconv.ParseFloat(res.(string), 64)
+	res, err := u.Send(ctx, "ZINCRBY", key, inc, member)
+	if err != nil {
+		return 0, err
+	}
+	return asFloat("ZINCRBY", res)
 }
 
 // ZLexCount returns the number of elements in the sorted set at key with a value between min and max.
@@ -112,7 +467,7 @@ func (u *Upstash) ZLexCount(ctx context.Context, key, min, max string) (int, err
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZLEXCOUNT", res)
 }
 
 // ZMScore returns the scores associated with the specified members in the sorted set stored at key.
@@ -126,13 +481,16 @@ func (u *Upstash) ZMScore(ctx context.Context, key string, members ...string) ([
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZMSCORE", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]float64, len(list))
 	for i, v := range list {
 		if v == nil {
 			result[i] = 0
 		} else {
-			result[i], _ = strconv.ParseFloat(v.(string), 64)
+			result[i], _ = strconv.ParseFloat(toString(v), 64)
 		}
 	}
 	return result, nil
@@ -149,10 +507,13 @@ func (u *Upstash) ZPopMax(ctx context.Context, key string, count ...int) ([]stri
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZPOPMAX", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -168,10 +529,13 @@ func (u *Upstash) ZPopMin(ctx context.Context, key string, count ...int) ([]stri
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZPOPMIN", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -185,7 +549,7 @@ func (u *Upstash) ZRank(ctx context.Context, key, member string) (int, error) {
 	if res == nil {
 		return -1, nil
 	}
-	return int(res.(float64)), nil
+	return asInt("ZRANK", res)
 }
 
 // ZRemRangeByLex removes all elements in the sorted set stored at key between the lexicographical range specified by min and max.
@@ -194,7 +558,7 @@ func (u *Upstash) ZRemRangeByLex(ctx context.Context, key, min, max string) (int
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZREMRANGEBYLEX", res)
 }
 
 // ZRemRangeByRank removes all elements in the sorted set stored at key with rank between start and stop.
@@ -203,7 +567,7 @@ func (u *Upstash) ZRemRangeByRank(ctx context.Context, key string, start, stop i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZREMRANGEBYRANK", res)
 }
 
 // ZRemRangeByScore removes all elements in the sorted set stored at key with a score between min and max.
@@ -212,7 +576,7 @@ func (u *Upstash) ZRemRangeByScore(ctx context.Context, key string, min, max any
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZREMRANGEBYSCORE", res)
 }
 
 // ZRevRange returns the specified range of elements in the sorted set stored at key, with the scores ordered from high to low.
@@ -221,10 +585,13 @@ func (u *Upstash) ZRevRange(ctx context.Context, key string, start, stop int) ([
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZREVRANGE", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -238,7 +605,7 @@ func (u *Upstash) ZRevRank(ctx context.Context, key, member string) (int, error)
 	if res == nil {
 		return -1, nil
 	}
-	return int(res.(float64)), nil
+	return asInt("ZREVRANK", res)
 }
 
 // ZMPop pops one or multiple elements with the highest or lowest scores from one or more sorted sets.
@@ -269,7 +636,7 @@ func (u *Upstash) ZInterCard(ctx context.Context, keys []string, limit ...int) (
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZINTERCARD", res)
 }
 
 // BZMPop is a blocking variant of ZMPOP.
@@ -300,10 +667,13 @@ func (u *Upstash) BZPopMax(ctx context.Context, timeout int64, keys ...string) (
 	if res == nil {
 		return nil, nil
 	}
-	list := res.([]any)
+	list, err := asList("BZPOPMAX", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -322,10 +692,13 @@ func (u *Upstash) BZPopMin(ctx context.Context, timeout int64, keys ...string) (
 	if res == nil {
 		return nil, nil
 	}
-	list := res.([]any)
+	list, err := asList("BZPOPMIN", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -342,10 +715,13 @@ func (u *Upstash) ZUnion(ctx context.Context, numKeys int, keys []string, args .
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZUNION", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -362,10 +738,13 @@ func (u *Upstash) ZInter(ctx context.Context, numKeys int, keys []string, args .
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZINTER", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -382,7 +761,7 @@ func (u *Upstash) ZUnionStore(ctx context.Context, destination string, numKeys i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZUNIONSTORE", res)
 }
 
 // ZInterStore is equal to ZINTER, but instead of returning the resulting set, it is stored in destination.
@@ -397,7 +776,7 @@ func (u *Upstash) ZInterStore(ctx context.Context, destination string, numKeys i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("ZINTERSTORE", res)
 }
 
 // ZRevRangeByLex returns all the elements in the sorted set at key with a value between max and min.
@@ -411,10 +790,13 @@ func (u *Upstash) ZRevRangeByLex(ctx context.Context, key, max, min string, coun
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZREVRANGEBYLEX", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
@@ -430,10 +812,122 @@ func (u *Upstash) ZRevRangeByScore(ctx context.Context, key string, max, min any
 	if err != nil {
 		return nil, err
 	}
-	list := res.([]any)
+	list, err := asList("ZREVRANGEBYSCORE", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// ZRangeByScore returns all the elements in the sorted set at key with a score between min and max.
+// Bounds may be numbers or strings such as "(5", "-inf" and "+inf". An optional count limits the number of elements returned.
+func (u *Upstash) ZRangeByScore(ctx context.Context, key string, min, max any, count ...int) ([]string, error) {
+	args := make([]any, 0, 3+len(count)*2)
+	args = append(args, key, min, max)
+	if len(count) > 0 {
+		args = append(args, "LIMIT", 0, count[0])
+	}
+	if len(count) > 0 && count[0] >= 0 {
+		if err := u.checkCollectionSize("ZRANGEBYSCORE", count[0]); err != nil {
+			return nil, err
+		}
+	} else if err := u.checkCountedSize(ctx, "ZRANGEBYSCORE", "ZCOUNT", key, min, max); err != nil {
+		return nil, err
+	}
+	res, err := u.Send(ctx, "ZRANGEBYSCORE", args...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("ZRANGEBYSCORE", res)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCollectionSize("ZRANGEBYSCORE", len(list)); err != nil {
+		return nil, err
+	}
 	result := make([]string, len(list))
 	for i, v := range list {
-		result[i] = v.(string)
+		result[i] = toString(v)
 	}
 	return result, nil
 }
+
+// ZRangeByLex returns all the elements in the sorted set at key with a value between min and max,
+// e.g. "[a" and "(c", or "-" and "+". An optional count limits the number of elements returned.
+func (u *Upstash) ZRangeByLex(ctx context.Context, key, min, max string, count ...int) ([]string, error) {
+	args := make([]any, 0, 3+len(count)*2)
+	args = append(args, key, min, max)
+	if len(count) > 0 {
+		args = append(args, "LIMIT", 0, count[0])
+	}
+	if len(count) > 0 && count[0] >= 0 {
+		if err := u.checkCollectionSize("ZRANGEBYLEX", count[0]); err != nil {
+			return nil, err
+		}
+	} else if err := u.checkCountedSize(ctx, "ZRANGEBYLEX", "ZLEXCOUNT", key, min, max); err != nil {
+		return nil, err
+	}
+	res, err := u.Send(ctx, "ZRANGEBYLEX", args...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("ZRANGEBYLEX", res)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkCollectionSize("ZRANGEBYLEX", len(list)); err != nil {
+		return nil, err
+	}
+	result := make([]string, len(list))
+	for i, v := range list {
+		result[i] = toString(v)
+	}
+	return result, nil
+}
+
+// BZPopMaxOK is like BZPopMax but reports a timeout explicitly.
+// ok is false when no member became available before the timeout expired.
+func (u *Upstash) BZPopMaxOK(ctx context.Context, timeout int64, keys ...string) (key, member string, score float64, ok bool, err error) {
+	res, err := u.BZPopMax(ctx, timeout, keys...)
+	if err != nil || len(res) < 3 {
+		return "", "", 0, false, err
+	}
+	score, err = strconv.ParseFloat(res[2], 64)
+	if err != nil {
+		return "", "", 0, false, err
+	}
+	return res[0], res[1], score, true, nil
+}
+
+// BZPopMinOK is like BZPopMin but reports a timeout explicitly.
+// ok is false when no member became available before the timeout expired.
+func (u *Upstash) BZPopMinOK(ctx context.Context, timeout int64, keys ...string) (key, member string, score float64, ok bool, err error) {
+	res, err := u.BZPopMin(ctx, timeout, keys...)
+	if err != nil || len(res) < 3 {
+		return "", "", 0, false, err
+	}
+	score, err = strconv.ParseFloat(res[2], 64)
+	if err != nil {
+		return "", "", 0, false, err
+	}
+	return res[0], res[1], score, true, nil
+}
+
+// formatScore converts a score into a command argument.
+// JSON cannot represent infinities, so they are sent using the Redis "+inf"/"-inf" notation.
+func formatScore(score float64) (any, error) {
+	switch {
+	case math.IsNaN(score):
+		return nil, fmt.Errorf("invalid score: NaN")
+	case math.IsInf(score, 1):
+		return "+inf", nil
+	case math.IsInf(score, -1):
+		return "-inf", nil
+	default:
+		return score, nil
+	}
+}
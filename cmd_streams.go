@@ -15,7 +15,23 @@ func (u *Upstash) XAdd(ctx context.Context, key, id string, values map[string]st
 	if err != nil {
 		return "", err
 	}
-	return res.(string), nil
+	return asString("XADD", res)
+}
+
+// XAddCapped appends an entry with an auto-generated ID to the stream at key, trimming the stream
+// to about maxLen entries with MAXLEN ~. Approximate trimming only removes whole macro nodes,
+// so the stream may briefly hold a few more than maxLen entries, which makes it a cheap ring buffer.
+func (u *Upstash) XAddCapped(ctx context.Context, key string, values map[string]string, maxLen int) (string, error) {
+	args := make([]any, 0, 5+len(values)*2)
+	args = append(args, key, "MAXLEN", "~", maxLen, "*")
+	for k, v := range values {
+		args = append(args, k, v)
+	}
+	res, err := u.Send(ctx, "XADD", args...)
+	if err != nil {
+		return "", err
+	}
+	return asString("XADD", res)
 }
 
 // XLen returns the number of entries of a stream.
@@ -24,7 +40,16 @@ func (u *Upstash) XLen(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("XLEN", res)
+}
+
+// XLenInt64 is like XLen but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) XLenInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "XLEN", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("XLEN", res)
 }
 
 // XRange returns the stream entries matching a range of IDs.
@@ -59,19 +84,29 @@ func (u *Upstash) parseStreamMessages(res any) ([]StreamMessage, error) {
 	if res == nil {
 		return []StreamMessage{}, nil
 	}
-	list := res.([]any)
+	list, err := asList("stream", res)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]StreamMessage, len(list))
 	for i, v := range list {
-		entry := v.([]any)
-		id := entry[0].(string)
-		fieldsRaw := entry[1].([]any)
+		entry, ok := v.([]any)
+		if !ok || len(entry) < 2 {
+			return nil, unexpectedType("stream entry", v)
+		}
+		id := toString(entry[0])
+		fieldsRaw, _ := entry[1].([]any)
 		fields := make(map[string]string, len(fieldsRaw)/2)
-		for j := 0; j < len(fieldsRaw); j += 2 {
-			fields[fieldsRaw[j].(string)] = fieldsRaw[j+1].(string)
+		ordered := make([]KV, 0, len(fieldsRaw)/2)
+		for j := 0; j+1 < len(fieldsRaw); j += 2 {
+			kv := KV{Key: toString(fieldsRaw[j]), Value: toString(fieldsRaw[j+1])}
+			fields[kv.Key] = kv.Value
+			ordered = append(ordered, kv)
 		}
 		result[i] = StreamMessage{
-			ID:     id,
-			Values: fields,
+			ID:            id,
+			Values:        fields,
+			ValuesOrdered: ordered,
 		}
 	}
 	return result, nil
@@ -88,7 +123,50 @@ func (u *Upstash) XAck(ctx context.Context, key, group string, ids ...string) (i
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("XACK", res)
+}
+
+// XAckDel acknowledges and deletes the given entries of the stream at key for the consumer group (Redis 8.2+).
+// It returns one status per id: -1 if the id does not exist, 1 if the entry was acknowledged and deleted,
+// and 2 if it was acknowledged but not deleted because it is still referenced (with Acked).
+func (u *Upstash) XAckDel(ctx context.Context, key, group string, opts XAckDelOptions, ids ...string) ([]int, error) {
+	args := []any{key, group}
+	args = append(args, xDelRefArgs(opts, ids)...)
+	res, err := u.Send(ctx, "XACKDEL", args...)
+	if err != nil {
+		return nil, err
+	}
+	return u.parseIntSlice("XACKDEL", res)
+}
+
+// XDelEx deletes the given entries of the stream at key with control over consumer group references (Redis 8.2+).
+// It returns one status per id: -1 if the id does not exist, 1 if the entry was deleted,
+// and 2 if it was not deleted because it is still referenced (with Acked).
+func (u *Upstash) XDelEx(ctx context.Context, key string, opts XAckDelOptions, ids ...string) ([]int, error) {
+	args := []any{key}
+	args = append(args, xDelRefArgs(opts, ids)...)
+	res, err := u.Send(ctx, "XDELEX", args...)
+	if err != nil {
+		return nil, err
+	}
+	return u.parseIntSlice("XDELEX", res)
+}
+
+func xDelRefArgs(opts XAckDelOptions, ids []string) []any {
+	args := make([]any, 0, 3+len(ids))
+	switch {
+	case opts.KeepRef:
+		args = append(args, "KEEPREF")
+	case opts.DelRef:
+		args = append(args, "DELREF")
+	case opts.Acked:
+		args = append(args, "ACKED")
+	}
+	args = append(args, "IDS", len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return args
 }
 
 // XDel removes the specified entries from a stream.
@@ -102,7 +180,7 @@ func (u *Upstash) XDel(ctx context.Context, key string, ids ...string) (int, err
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("XDEL", res)
 }
 
 // XGroup manages consumer groups.
@@ -113,6 +191,43 @@ func (u *Upstash) XGroup(ctx context.Context, subcommand string, key string, gro
 	return u.Send(ctx, "XGROUP", fullArgs...)
 }
 
+// XGroupCreateConsumer creates the consumer named consumer in the consumer group of the stream stored at key.
+// It returns 1 if the consumer was created and 0 if it already existed.
+func (u *Upstash) XGroupCreateConsumer(ctx context.Context, key, group, consumer string) (int, error) {
+	res, err := u.XGroup(ctx, "CREATECONSUMER", key, group, consumer)
+	if err != nil {
+		return 0, err
+	}
+	return asInt("XGROUP CREATECONSUMER", res)
+}
+
+// XGroupListConsumers returns the consumers of the consumer group of the stream stored at key.
+func (u *Upstash) XGroupListConsumers(ctx context.Context, key, group string) ([]XConsumerInfo, error) {
+	res, err := u.XInfo(ctx, "CONSUMERS", key, group)
+	if err != nil {
+		return nil, err
+	}
+	list, err := asList("XINFO CONSUMERS", res)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]XConsumerInfo, 0, len(list))
+	for _, c := range list {
+		cm := flatMap(c)
+		consumer := XConsumerInfo{
+			Name:    toString(cm["name"]),
+			Pending: toInt64(cm["pending"]),
+			Idle:    toInt64(cm["idle"]),
+		}
+		if v := cm["inactive"]; v != nil {
+			n := toInt64(v)
+			consumer.Inactive = &n
+		}
+		result = append(result, consumer)
+	}
+	return result, nil
+}
+
 // XRead reads data from one or multiple streams.
 func (u *Upstash) XRead(ctx context.Context, count int, block int, streams map[string]string) (any, error) {
 	args := make([]any, 0)
@@ -143,7 +258,7 @@ func (u *Upstash) XTrim(ctx context.Context, key string, strategy string, thresh
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("XTRIM", res)
 }
 
 // XAutoClaim claims pending stream entries that match the criteria.
@@ -173,6 +288,112 @@ func (u *Upstash) XInfo(ctx context.Context, subcommand string, key string, args
 	return u.Send(ctx, "XINFO", fullArgs...)
 }
 
+// XInfoGroups returns the consumer groups of the stream stored at key.
+func (u *Upstash) XInfoGroups(ctx context.Context, key string) ([]XGroupInfo, error) {
+	res, err := u.XInfo(ctx, "GROUPS", key)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := res.([]any)
+	result := make([]XGroupInfo, 0, len(list))
+	for _, g := range list {
+		gm := flatMap(g)
+		group := XGroupInfo{
+			Name:            toString(gm["name"]),
+			Consumers:       toInt64(gm["consumers"]),
+			Pending:         toInt64(gm["pending"]),
+			LastDeliveredID: toString(gm["last-delivered-id"]),
+		}
+		if v := gm["entries-read"]; v != nil {
+			n := toInt64(v)
+			group.EntriesRead = &n
+		}
+		if v := gm["lag"]; v != nil {
+			n := toInt64(v)
+			group.Lag = &n
+		}
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// XInfoStreamFull returns detailed information about a stream, including its consumer groups,
+// their consumers and pending entries. count limits the number of entries and pending entries
+// returned per list; zero uses the server default.
+func (u *Upstash) XInfoStreamFull(ctx context.Context, key string, count int) (XStreamFullInfo, error) {
+	args := []any{"FULL"}
+	if count > 0 {
+		args = append(args, "COUNT", count)
+	}
+	res, err := u.XInfo(ctx, "STREAM", key, args...)
+	if err != nil {
+		return XStreamFullInfo{}, err
+	}
+	raw := flatMap(res)
+	info := XStreamFullInfo{
+		Length:               toInt64(raw["length"]),
+		LastGeneratedID:      toString(raw["last-generated-id"]),
+		MaxDeletedEntryID:    toString(raw["max-deleted-entry-id"]),
+		EntriesAdded:         toInt64(raw["entries-added"]),
+		RecordedFirstEntryID: toString(raw["recorded-first-entry-id"]),
+		Raw:                  raw,
+	}
+	info.Entries, err = u.parseStreamMessages(raw["entries"])
+	if err != nil {
+		return XStreamFullInfo{}, err
+	}
+	groups, _ := raw["groups"].([]any)
+	for _, g := range groups {
+		gm := flatMap(g)
+		group := XStreamGroupFull{
+			Name:            toString(gm["name"]),
+			LastDeliveredID: toString(gm["last-delivered-id"]),
+			EntriesRead:     toInt64(gm["entries-read"]),
+			Lag:             toInt64(gm["lag"]),
+			PelCount:        toInt64(gm["pel-count"]),
+		}
+		pel, _ := gm["pel"].([]any)
+		for _, p := range pel {
+			e, _ := p.([]any)
+			if len(e) < 4 {
+				continue
+			}
+			group.Pending = append(group.Pending, XPendingEntry{
+				ID:            toString(e[0]),
+				Consumer:      toString(e[1]),
+				DeliveryTime:  toInt64(e[2]),
+				DeliveryCount: toInt64(e[3]),
+			})
+		}
+		consumers, _ := gm["consumers"].([]any)
+		for _, c := range consumers {
+			cm := flatMap(c)
+			consumer := XStreamConsumerFull{
+				Name:       toString(cm["name"]),
+				SeenTime:   toInt64(cm["seen-time"]),
+				ActiveTime: toInt64(cm["active-time"]),
+				PelCount:   toInt64(cm["pel-count"]),
+			}
+			cpel, _ := cm["pel"].([]any)
+			for _, p := range cpel {
+				e, _ := p.([]any)
+				if len(e) < 3 {
+					continue
+				}
+				consumer.Pending = append(consumer.Pending, XPendingEntry{
+					ID:            toString(e[0]),
+					Consumer:      consumer.Name,
+					DeliveryTime:  toInt64(e[1]),
+					DeliveryCount: toInt64(e[2]),
+				})
+			}
+			group.Consumers = append(group.Consumers, consumer)
+		}
+		info.Groups = append(info.Groups, group)
+	}
+	return info, nil
+}
+
 // XPending returns information about pending messages in a consumer group.
 func (u *Upstash) XPending(ctx context.Context, key, group string, args ...any) (any, error) {
 	fullArgs := make([]any, 0, 2+len(args))
@@ -204,3 +425,74 @@ func (u *Upstash) XReadGroup(ctx context.Context, options XReadGroupOptions, str
 	args = append(args, ids...)
 	return u.Send(ctx, "XREADGROUP", args...)
 }
+
+// StreamIterator pages through the entries of a stream.
+// Call Next to advance, Message to read the current entry and Err to check for errors once Next returns false.
+type StreamIterator struct {
+	u         *Upstash
+	ctx       context.Context
+	key       string
+	start     string
+	stop      string
+	batchSize int
+	batch     []StreamMessage
+	current   StreamMessage
+	done      bool
+	err       error
+}
+
+// XRangeIter returns an iterator over the stream entries matching a range of IDs.
+// Entries are fetched in pages of batchSize, so arbitrarily large streams can be replayed
+// without loading them into memory at once.
+func (u *Upstash) XRangeIter(ctx context.Context, key, start, stop string, batchSize int) *StreamIterator {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &StreamIterator{
+		u:         u,
+		ctx:       ctx,
+		key:       key,
+		start:     start,
+		stop:      stop,
+		batchSize: batchSize,
+	}
+}
+
+// Next advances the iterator to the next entry. It returns false when the range is exhausted or an error occurred.
+func (it *StreamIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.batch) == 0 {
+		if it.done {
+			return false
+		}
+		batch, err := it.u.XRange(it.ctx, it.key, it.start, it.stop, it.batchSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(batch) < it.batchSize {
+			it.done = true
+		}
+		if len(batch) == 0 {
+			return false
+		}
+		// Continue after the last returned entry (exclusive range).
+		it.start = "(" + batch[len(batch)-1].ID
+		it.batch = batch
+	}
+	it.current = it.batch[0]
+	it.batch = it.batch[1:]
+	return true
+}
+
+// Message returns the current entry.
+func (it *StreamIterator) Message() StreamMessage {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *StreamIterator) Err() error {
+	return it.err
+}
@@ -3,7 +3,8 @@ package upstash
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"math/rand/v2"
+	"time"
 
 	"github.com/claywarren/upstash-go/internal/rest"
 )
@@ -16,7 +17,7 @@ func (u *Upstash) Append(ctx context.Context, key string, value string) (int, er
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("APPEND", res)
 }
 
 // Decr decrements the number stored at key by one.
@@ -27,7 +28,16 @@ func (u *Upstash) Decr(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("DECR", res)
+}
+
+// DecrInt64 is like Decr but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) DecrInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "DECR", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("DECR", res)
 }
 
 // DecrBy decrements the number stored at key by the provided decrement value.
@@ -38,7 +48,16 @@ func (u *Upstash) DecrBy(ctx context.Context, key string, decrement int) (int, e
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("DECRBY", res)
+}
+
+// DecrByInt64 is like DecrBy but takes and returns int64 values, which cannot overflow on 32-bit platforms.
+func (u *Upstash) DecrByInt64(ctx context.Context, key string, decrement int64) (int64, error) {
+	res, err := u.Send(ctx, "DECRBY", key, decrement)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("DECRBY", res)
 }
 
 // Get retrieves the value of a key.
@@ -53,7 +72,7 @@ func (u *Upstash) Get(ctx context.Context, key string) (string, error) {
 		return "", nil
 	}
 
-	return res.(string), nil
+	return asString("GET", res)
 }
 
 // GetEx retrieves the value of a key and optionally sets its expiration.
@@ -82,7 +101,7 @@ func (u *Upstash) GetEx(ctx context.Context, key string, options GetEXOptions) (
 		return "", nil
 	}
 
-	return res.(string), nil
+	return asString("GETEX", res)
 }
 
 // GetRange returns a substring of the string value stored at a key.
@@ -94,7 +113,44 @@ func (u *Upstash) GetRange(ctx context.Context, key string, start int, end int)
 		return "", err
 	}
 
-	return res.(string), nil
+	return asString("GETRANGE", res)
+}
+
+// GetBytes returns the value of key as raw bytes, with found set to false if the key does not exist.
+// Enable EnableBase64 when values may be arbitrary binary data (e.g. serialized protobuf or gob):
+// responses are then transferred base64-encoded and decoded back to the exact bytes, whereas plain
+// JSON responses cannot carry invalid UTF-8.
+func (u *Upstash) GetBytes(ctx context.Context, key string) (value []byte, found bool, err error) {
+	res, err := u.client.Read(ctx, rest.Request{
+		Path: []string{"get", key},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if res == nil {
+		return nil, false, nil
+	}
+	s, ok := res.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected return type for get: %T", res)
+	}
+	return []byte(s), true, nil
+}
+
+// GetPrefix returns the first n characters of the string value stored at a key.
+func (u *Upstash) GetPrefix(ctx context.Context, key string, n int) (string, error) {
+	if n <= 0 {
+		return "", nil
+	}
+	return u.GetRange(ctx, key, 0, n-1)
+}
+
+// GetSuffix returns the last n characters of the string value stored at a key.
+func (u *Upstash) GetSuffix(ctx context.Context, key string, n int) (string, error) {
+	if n <= 0 {
+		return "", nil
+	}
+	return u.GetRange(ctx, key, -n, -1)
 }
 
 // GetSet atomically sets a key to a value and returns the old value.
@@ -106,7 +162,7 @@ func (u *Upstash) GetSet(ctx context.Context, key string, value string) (string,
 		return "", err
 	}
 
-	return res.(string), nil
+	return asString("GETSET", res)
 }
 
 // Incr increments the number stored at key by one.
@@ -117,7 +173,16 @@ func (u *Upstash) Incr(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("INCR", res)
+}
+
+// IncrInt64 is like Incr but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) IncrInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("INCR", res)
 }
 
 // IncrBy increments the number stored at key by the provided increment value.
@@ -128,7 +193,37 @@ func (u *Upstash) IncrBy(ctx context.Context, key string, increment int) (int, e
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("INCRBY", res)
+}
+
+// IncrByInt64 is like IncrBy but takes and returns int64 values, which cannot overflow on 32-bit platforms.
+func (u *Upstash) IncrByInt64(ctx context.Context, key string, increment int64) (int64, error) {
+	res, err := u.Send(ctx, "INCRBY", key, increment)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("INCRBY", res)
+}
+
+// IncrChecked is like Incr, but returns an error wrapping ErrNotInteger
+// if the value stored at key is not an integer.
+func (u *Upstash) IncrChecked(ctx context.Context, key string) (int, error) {
+	res, err := u.Incr(ctx, key)
+	return res, wrapNotInteger(err)
+}
+
+// IncrByChecked is like IncrBy, but returns an error wrapping ErrNotInteger
+// if the value stored at key is not an integer.
+func (u *Upstash) IncrByChecked(ctx context.Context, key string, increment int) (int, error) {
+	res, err := u.IncrBy(ctx, key, increment)
+	return res, wrapNotInteger(err)
+}
+
+// DecrByChecked is like DecrBy, but returns an error wrapping ErrNotInteger
+// if the value stored at key is not an integer.
+func (u *Upstash) DecrByChecked(ctx context.Context, key string, decrement int) (int, error) {
+	res, err := u.DecrBy(ctx, key, decrement)
+	return res, wrapNotInteger(err)
 }
 
 // IncrByFloat increments the string representing a floating point number stored at key by the provided increment.
@@ -139,15 +234,35 @@ func (u *Upstash) IncrByFloat(ctx context.Context, key string, increment float64
 	if err != nil {
 		return 0, err
 	}
-	f, err := strconv.ParseFloat(res.(string), 64)
-	if err != nil {
-		return 0, err
-	}
-	return f, nil
+	return asFloat("INCRBYFLOAT", res)
 }
 
 // MGet returns the values of all specified keys.
+// Key lists beyond the MGET batch size (see Options.MaxBatchSize) are split into chunks sent as
+// a single pipeline, and the values are concatenated in the order of keys.
 func (u *Upstash) MGet(ctx context.Context, keys []string) ([]string, error) {
+	if size := u.batchSize("MGET"); size > 0 && len(keys) > size {
+		items := make([][]any, len(keys))
+		for i, k := range keys {
+			items[i] = []any{k}
+		}
+		chunks, err := u.sendBatched(ctx, "MGET", nil, items)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(keys))
+		for _, chunk := range chunks {
+			list, err := asList("MGET", chunk)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range list {
+				values = append(values, fmt.Sprint(value))
+			}
+		}
+		return values, nil
+	}
+
 	res, err := u.client.Read(ctx, rest.Request{
 		Path: append([]string{"mget"}, keys...),
 	})
@@ -155,8 +270,12 @@ func (u *Upstash) MGet(ctx context.Context, keys []string) ([]string, error) {
 		return nil, err
 	}
 
+	list, err := asList("MGET", res)
+	if err != nil {
+		return nil, err
+	}
 	values := make([]string, len(keys))
-	for i, value := range res.([]any) {
+	for i, value := range list {
 		values[i] = fmt.Sprint(value)
 	}
 
@@ -192,7 +311,13 @@ func (u *Upstash) MSetNX(ctx context.Context, kvPairs []KV) (int, error) {
 	if res == nil {
 		return 0, nil
 	}
-	return int(res.(float64)), nil
+	return asInt("MSETNX", res)
+}
+
+// MSetNXBool sets the given keys to their respective values if none of the keys exist and reports whether they were set.
+func (u *Upstash) MSetNXBool(ctx context.Context, kvPairs []KV) (bool, error) {
+	res, err := u.MSetNX(ctx, kvPairs)
+	return res == 1, err
 }
 
 // PSetEX sets a key to a value with a provided expiration time in milliseconds.
@@ -213,25 +338,151 @@ func (u *Upstash) Set(ctx context.Context, key string, value string) error {
 
 // SetWithOptions sets a key to hold the string value with additional options.
 func (u *Upstash) SetWithOptions(ctx context.Context, key string, value string, options SetOptions) error {
+	body, err := setBody(key, value, options)
+	if err != nil {
+		return err
+	}
+
+	_, err = u.client.Write(ctx, rest.Request{
+		Body: body,
+	})
+	if err != nil {
+		return fmt.Errorf("error %s: %w", body, err)
+	}
+	return nil
+}
+
+// SetGetOld sets a key with additional options and returns the value previously stored at key.
+// existed is false if the key did not exist. With NX, when the key exists the SET is skipped but
+// its current value is still returned (this combination requires Redis 7.0 or later).
+func (u *Upstash) SetGetOld(ctx context.Context, key string, value string, options SetOptions) (old string, existed bool, err error) {
+	options.GET = true
+	body, err := setBody(key, value, options)
+	if err != nil {
+		return "", false, err
+	}
+
+	res, err := u.client.Write(ctx, rest.Request{
+		Body: body,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if res == nil {
+		return "", false, nil
+	}
+	old, ok := res.(string)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected return type for set: %T", res)
+	}
+	return old, true, nil
+}
+
+// SetAndGetTTL sets a key with additional options and returns its TTL in a single round trip.
+// applied is false when the SET was not performed because of the NX or XX condition.
+// ttl follows the TTL command: -1 if the key has no expiry and -2 if it does not exist.
+func (u *Upstash) SetAndGetTTL(ctx context.Context, key string, value string, options SetOptions) (applied bool, ttl int, err error) {
+	if options.GET {
+		return false, 0, fmt.Errorf("SetAndGetTTL does not support the GET option")
+	}
+	body, err := setBody(key, value, options)
+	if err != nil {
+		return false, 0, err
+	}
+	args := make([]any, 0, len(body)-1)
+	for _, b := range body[1:] {
+		args = append(args, b)
+	}
+
+	pipe := u.Pipeline()
+	pipe.Push(body[0], args...)
+	pipe.Push("ttl", key)
+	res, err := pipe.Exec(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected pipeline result length: %d", len(res))
+	}
+
+	setRes, err := pipelineResult(res[0])
+	if err != nil {
+		return false, 0, err
+	}
+	ttlRes, err := pipelineResult(res[1])
+	if err != nil {
+		return false, 0, err
+	}
+	ttl, err = asInt("TTL", ttlRes)
+	if err != nil {
+		return false, 0, err
+	}
+	return setRes != nil, ttl, nil
+}
+
+// SetWithJitterTTL sets a key to hold the string value with a TTL picked at random in [baseTTL, baseTTL+jitter].
+// Spreading the expiry of keys cached at the same time avoids them all expiring together (cache stampede).
+// baseTTL must be at least one millisecond and jitter must not be negative.
+func (u *Upstash) SetWithJitterTTL(ctx context.Context, key string, value string, baseTTL, jitter time.Duration) error {
+	if baseTTL < time.Millisecond {
+		return fmt.Errorf("invalid ttl: %s", baseTTL)
+	}
+	if jitter < 0 {
+		return fmt.Errorf("invalid jitter: %s", jitter)
+	}
+	ttl := baseTTL
+	if jitter > 0 {
+		ttl += time.Duration(rand.Int64N(int64(jitter) + 1))
+	}
+	return u.SetWithOptions(ctx, key, value, SetOptions{PX: int(ttl.Milliseconds())})
+}
+
+// SetWithTTL sets key to value with the given expiration, using EX for whole-second TTLs and PX otherwise.
+// The TTL is truncated to milliseconds and must be at least one millisecond.
+func (u *Upstash) SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if ttl < time.Millisecond {
+		return fmt.Errorf("invalid ttl: %s", ttl)
+	}
+	options := SetOptions{PX: int(ttl.Milliseconds())}
+	if ttl%time.Second == 0 {
+		options = SetOptions{EX: int(ttl / time.Second)}
+	}
+	return u.SetWithOptions(ctx, key, value, options)
+}
+
+func setBody(key string, value string, options SetOptions) ([]string, error) {
+	expiries := 0
+	for _, set := range []bool{options.EX != 0, options.PX != 0, options.EXAT != 0, options.PXAT != 0, options.KEEPTTL} {
+		if set {
+			expiries++
+		}
+	}
+	if expiries > 1 {
+		return nil, fmt.Errorf("invalid set options: only one of EX, PX, EXAT, PXAT and KEEPTTL may be set")
+	}
+
 	body := []string{"set", key, value}
-	if options.EX != 0 {
+	switch {
+	case options.EX != 0:
 		body = append(body, "ex", fmt.Sprintf("%d", options.EX))
-	} else if options.PX != 0 {
+	case options.PX != 0:
 		body = append(body, "px", fmt.Sprintf("%d", options.PX))
+	case options.EXAT != 0:
+		body = append(body, "exat", fmt.Sprintf("%d", options.EXAT))
+	case options.PXAT != 0:
+		body = append(body, "pxat", fmt.Sprintf("%d", options.PXAT))
+	case options.KEEPTTL:
+		body = append(body, "keepttl")
 	}
 	if options.NX {
 		body = append(body, "nx")
 	} else if options.XX {
 		body = append(body, "xx")
 	}
-
-	_, err := u.client.Write(ctx, rest.Request{
-		Body: body,
-	})
-	if err != nil {
-		return fmt.Errorf("error %s: %w", body, err)
+	if options.GET {
+		body = append(body, "get")
 	}
-	return nil
+	return body, nil
 }
 
 // SetEX sets a key to hold the string value with a provided expiration time in seconds.
@@ -250,7 +501,13 @@ func (u *Upstash) SetNX(ctx context.Context, key string, value string) (int, err
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("SETNX", res)
+}
+
+// SetNXBool sets a key to hold the string value if the key does not exist and reports whether it was set.
+func (u *Upstash) SetNXBool(ctx context.Context, key string, value string) (bool, error) {
+	res, err := u.SetNX(ctx, key, value)
+	return res == 1, err
 }
 
 // SetRange overwrites part of the string stored at a key, starting at the specified offset.
@@ -270,7 +527,16 @@ func (u *Upstash) StrLen(ctx context.Context, key string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(res.(float64)), nil
+	return asInt("STRLEN", res)
+}
+
+// StrLenInt64 is like StrLen but returns an int64, which cannot overflow on 32-bit platforms.
+func (u *Upstash) StrLenInt64(ctx context.Context, key string) (int64, error) {
+	res, err := u.Send(ctx, "STRLEN", key)
+	if err != nil {
+		return 0, err
+	}
+	return asInt64("STRLEN", res)
 }
 
 // GetDel gets the value of key and deletes the key.
@@ -282,5 +548,5 @@ func (u *Upstash) GetDel(ctx context.Context, key string) (string, error) {
 	if res == nil {
 		return "", nil
 	}
-	return res.(string), nil
+	return asString("GETDEL", res)
 }
@@ -0,0 +1,123 @@
+package upstash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/claywarren/upstash-go/internal/rest"
+)
+
+// ErrNotInteger is returned by the checked numeric helpers when the value stored at a key
+// is not an integer or is out of range.
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// ErrKeyNotFound is returned by the strict helpers when the key they operate on does not exist.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrNil is returned when a command that is expected to return a value matched nothing.
+var ErrNil = errors.New("nil reply")
+
+// ErrWrongType is returned, wrapped, when a command is run against a key holding the wrong kind of value.
+var ErrWrongType = rest.ErrWrongType
+
+// ErrNotBusy is returned, wrapped, by ScriptKill and FunctionKill when there is nothing to kill.
+var ErrNotBusy = rest.ErrNotBusy
+
+// ErrNoPerm is returned, wrapped, when the token's user is not allowed to run a command,
+// e.g. CLIENT KILL on a database that restricts it.
+var ErrNoPerm = rest.ErrNoPerm
+
+// ErrAuth is returned, wrapped, when authentication fails: a rejected AUTH, a command sent
+// before authenticating, or a token refused with 401 Unauthorized.
+var ErrAuth = rest.ErrAuth
+
+// ErrUnexpectedResponse is returned, wrapped, when the server responds with a JSON object that has
+// neither a result nor an error. The error message includes the raw body.
+var ErrUnexpectedResponse = rest.ErrUnexpectedResponse
+
+// ErrCollectionTooLarge is returned when a collection command would exceed Options.MaxCollectionResult.
+var ErrCollectionTooLarge = errors.New("collection result exceeds MaxCollectionResult")
+
+// checkCollectionSize returns ErrCollectionTooLarge if n elements exceed the configured cap.
+func (u *Upstash) checkCollectionSize(command string, n int) error {
+	if u.maxCollectionResult > 0 && n > u.maxCollectionResult {
+		return fmt.Errorf("%s: %w: %d > %d", command, ErrCollectionTooLarge, n, u.maxCollectionResult)
+	}
+	return nil
+}
+
+// checkRangeSize applies checkCollectionSize to the index range start..stop of the collection at key.
+// When the width depends on the collection size, e.g. 0 to -1, the size is first read with sizeCommand
+// (LLEN or ZCARD), which costs one extra request while a cap is set.
+func (u *Upstash) checkRangeSize(ctx context.Context, command, sizeCommand, key string, start, stop int) error {
+	if u.maxCollectionResult <= 0 {
+		return nil
+	}
+	if (start >= 0) == (stop >= 0) {
+		if stop < start {
+			return nil
+		}
+		return u.checkCollectionSize(command, stop-start+1)
+	}
+	res, err := u.Send(ctx, sizeCommand, key)
+	if err != nil {
+		return err
+	}
+	size, err := asInt(sizeCommand, res)
+	if err != nil {
+		return err
+	}
+	if start < 0 {
+		start = max(start+size, 0)
+	}
+	if stop < 0 {
+		stop += size
+	}
+	stop = min(stop, size-1)
+	if stop < start {
+		return nil
+	}
+	return u.checkCollectionSize(command, stop-start+1)
+}
+
+// checkCountedSize applies checkCollectionSize to the number of elements a range will return,
+// read up front with countCommand and args, e.g. ZCOUNT key min max. It sends nothing while no cap is set.
+func (u *Upstash) checkCountedSize(ctx context.Context, command, countCommand string, args ...any) error {
+	if u.maxCollectionResult <= 0 {
+		return nil
+	}
+	res, err := u.Send(ctx, countCommand, args...)
+	if err != nil {
+		return err
+	}
+	n, err := asInt(countCommand, res)
+	if err != nil {
+		return err
+	}
+	return u.checkCollectionSize(command, n)
+}
+
+// rankIndex returns v as a rank index, accepting ints and their string form.
+func rankIndex(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// wrapNotInteger wraps err with ErrNotInteger if the server rejected the value as a non-integer.
+func wrapNotInteger(err error) error {
+	if err != nil && strings.Contains(err.Error(), "not an integer") {
+		return fmt.Errorf("%w: %w", ErrNotInteger, err)
+	}
+	return err
+}
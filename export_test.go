@@ -0,0 +1,19 @@
+package upstash
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// SetHealthCheckAfter replaces the timer of the background health check and returns a function restoring it.
+func SetHealthCheckAfter(after func(time.Duration) <-chan time.Time) func() {
+	previous := healthCheckAfter
+	healthCheckAfter = after
+	return func() { healthCheckAfter = previous }
+}
+
+// NewHTTPClient exposes the construction of the default HTTP client.
+func NewHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return newHTTPClient(tlsConfig)
+}
@@ -0,0 +1,82 @@
+package upstash
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// healthCheckAfter waits for the next health check. It is replaced in tests to control time.
+var healthCheckAfter = time.After
+
+// healthChecker caches the result of PINGs sent periodically in the background.
+type healthChecker struct {
+	mu      sync.Mutex
+	healthy bool
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func (h *healthChecker) set(healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.healthy = healthy
+}
+
+func (h *healthChecker) get() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.healthy
+}
+
+func (h *healthChecker) close() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() { close(h.stop) })
+}
+
+// startHealthCheck pings the server right away and then every interval until StopHealthCheck is called.
+// Each PING must complete within interval to count as healthy.
+func (u *Upstash) startHealthCheck(interval time.Duration) {
+	h := &healthChecker{stop: make(chan struct{})}
+	u.health = h
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-h.stop
+			cancel()
+		}()
+		for {
+			pingCtx, pingCancel := context.WithTimeout(ctx, interval)
+			_, err := u.Ping(pingCtx)
+			pingCancel()
+			if ctx.Err() != nil {
+				return
+			}
+			h.set(err == nil)
+
+			select {
+			case <-healthCheckAfter(interval):
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+// IsHealthy reports whether the last background health check succeeded.
+// It does not contact the server, so it is cheap enough for frequent liveness probes.
+// It returns false until the first check completes, and always if Options.HealthCheckInterval is unset.
+func (u *Upstash) IsHealthy() bool {
+	return u.health.get()
+}
+
+// StopHealthCheck stops the background health check started by New. IsHealthy keeps reporting the last result.
+func (u *Upstash) StopHealthCheck() {
+	u.health.close()
+}
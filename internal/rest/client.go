@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // DefaultBackoff implements the TS client's exponential backoff: exp(retryCount) * 50ms
@@ -50,6 +52,13 @@ type upstashClient struct {
 	retries          int
 	backoff          func(int) time.Duration
 	latencyLogger    func(string, time.Duration)
+	requestTimeout   time.Duration
+	blockingTimeout  time.Duration
+	idempotencyKey   bool
+	useNumber        bool
+	requestLogger    func(string, string, []byte)
+	argEncoder       func(any) (string, error)
+	inspector        func(http.Header)
 }
 
 func New(
@@ -67,6 +76,26 @@ func New(
 	httpClient HTTPClient,
 	latencyLogger func(string, time.Duration),
 
+	// Timeouts applied to each request, depending on whether it contains a blocking command.
+	// Zero means no timeout.
+	requestTimeout time.Duration,
+	blockingTimeout time.Duration,
+
+	// Attach a unique Idempotency-Key header to each request, reused across its retries.
+	idempotencyKey bool,
+
+	// Decode numbers as json.Number instead of float64.
+	useNumber bool,
+
+	// Called with the method, URL and JSON body of each request right before it is sent.
+	requestLogger func(method, url string, body []byte),
+
+	// Renders each non-string command argument into a token. Nil leaves arguments to JSON marshaling.
+	argEncoder func(any) (string, error),
+
+	// Called with the headers of each response received, successful or not.
+	inspector func(header http.Header),
+
 ) Client {
 	return &upstashClient{
 		url,
@@ -78,20 +107,209 @@ func New(
 		retries,
 		backoff,
 		latencyLogger,
+		requestTimeout,
+		blockingTimeout,
+		idempotencyKey,
+		useNumber,
+		requestLogger,
+		argEncoder,
+		inspector,
 	}
 }
 
-// JSON marshal the body if present
-func marshalBody(body any) (io.Reader, error) {
-	var payload io.Reader = nil
-	if body != nil {
-		b, err := json.Marshal(body)
+// blockingCommands lists the commands that may block on the server.
+// XREAD and XREADGROUP only block when the BLOCK option is given.
+var blockingCommands = map[string]bool{
+	"BLPOP":      true,
+	"BRPOP":      true,
+	"BRPOPLPUSH": true,
+	"BLMOVE":     true,
+	"BLMPOP":     true,
+	"BZPOPMIN":   true,
+	"BZPOPMAX":   true,
+	"BZMPOP":     true,
+	"WAIT":       true,
+	"WAITAOF":    true,
+}
+
+// IsBlocking reports whether the command in body may block on the server.
+// For pipelines and transactions it reports whether any of the commands may block.
+func IsBlocking(body any) bool {
+	switch b := body.(type) {
+	case []any:
+		if len(b) == 0 {
+			return false
+		}
+		if _, ok := b[0].([]any); ok {
+			for _, cmd := range b {
+				if IsBlocking(cmd) {
+					return true
+				}
+			}
+			return false
+		}
+		name := strings.ToUpper(fmt.Sprint(b[0]))
+		if blockingCommands[name] {
+			return true
+		}
+		if name == "XREAD" || name == "XREADGROUP" {
+			for _, arg := range b[1:] {
+				if s, ok := arg.(string); ok && strings.EqualFold(s, "BLOCK") {
+					return true
+				}
+			}
+		}
+		return false
+	case [][]any:
+		for _, cmd := range b {
+			if IsBlocking(cmd) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		return len(b) > 0 && blockingCommands[strings.ToUpper(b[0])]
+	default:
+		return false
+	}
+}
+
+// readOnlyCommands lists the commands that never modify data, so replaying them is safe.
+var readOnlyCommands = map[string]bool{
+	"GET": true, "MGET": true, "GETRANGE": true, "STRLEN": true, "LCS": true,
+	"EXISTS": true, "TYPE": true, "TTL": true, "PTTL": true, "EXPIRETIME": true, "PEXPIRETIME": true,
+	"KEYS": true, "SCAN": true, "RANDOMKEY": true, "DBSIZE": true, "DUMP": true, "OBJECT": true, "SORT_RO": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HKEYS": true, "HVALS": true, "HLEN": true,
+	"HEXISTS": true, "HSTRLEN": true, "HSCAN": true, "HRANDFIELD": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true, "LPOS": true,
+	"SMEMBERS": true, "SISMEMBER": true, "SMISMEMBER": true, "SCARD": true, "SSCAN": true,
+	"SRANDMEMBER": true, "SINTER": true, "SUNION": true, "SDIFF": true, "SINTERCARD": true,
+	"ZRANGE": true, "ZRANGEBYSCORE": true, "ZRANGEBYLEX": true, "ZREVRANGE": true,
+	"ZREVRANGEBYSCORE": true, "ZREVRANGEBYLEX": true, "ZSCORE": true, "ZMSCORE": true, "ZCARD": true,
+	"ZCOUNT": true, "ZLEXCOUNT": true, "ZRANK": true, "ZREVRANK": true, "ZSCAN": true,
+	"ZRANDMEMBER": true, "ZINTER": true, "ZUNION": true, "ZDIFF": true, "ZINTERCARD": true,
+	"XRANGE": true, "XREVRANGE": true, "XLEN": true, "XREAD": true, "XINFO": true, "XPENDING": true,
+	"GEOPOS": true, "GEODIST": true, "GEOHASH": true, "GEOSEARCH": true,
+	"GEORADIUS_RO": true, "GEORADIUSBYMEMBER_RO": true,
+	"GETBIT": true, "BITCOUNT": true, "BITPOS": true, "BITFIELD_RO": true, "PFCOUNT": true,
+	"JSON.GET": true, "JSON.MGET": true, "JSON.TYPE": true, "JSON.STRLEN": true, "JSON.ARRLEN": true,
+	"JSON.OBJLEN": true, "JSON.OBJKEYS": true, "JSON.ARRINDEX": true, "JSON.RESP": true,
+	"EVAL_RO": true, "EVALSHA_RO": true, "FCALL_RO": true,
+	"PING": true, "ECHO": true, "TIME": true, "INFO": true, "ROLE": true, "LASTSAVE": true, "COMMAND": true,
+}
+
+// IsReadOnly reports whether the command in body never modifies data.
+// For pipelines and transactions it reports whether all of the commands are read-only.
+func IsReadOnly(body any) bool {
+	switch b := body.(type) {
+	case []any:
+		if len(b) == 0 {
+			return false
+		}
+		if _, ok := b[0].([]any); ok {
+			for _, cmd := range b {
+				if !IsReadOnly(cmd) {
+					return false
+				}
+			}
+			return true
+		}
+		return readOnlyCommands[strings.ToUpper(fmt.Sprint(b[0]))]
+	case [][]any:
+		if len(b) == 0 {
+			return false
+		}
+		for _, cmd := range b {
+			if !IsReadOnly(cmd) {
+				return false
+			}
+		}
+		return true
+	case []string:
+		return len(b) > 0 && readOnlyCommands[strings.ToUpper(b[0])]
+	default:
+		return false
+	}
+}
+
+// JSON marshal the body if present, rendering command arguments with the argument encoder if there is one
+func (c *upstashClient) marshalBody(body any) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	if c.argEncoder != nil {
+		encoded, err := c.encodeArgs(body)
 		if err != nil {
 			return nil, err
 		}
-		payload = bytes.NewBuffer(b)
+		body = encoded
 	}
-	return payload, nil
+	return json.Marshal(body)
+}
+
+// encodeArgs returns a copy of the command or pipeline body with every non-string argument
+// after the command name rendered by the argument encoder.
+func (c *upstashClient) encodeArgs(body any) (any, error) {
+	switch b := body.(type) {
+	case []any:
+		if len(b) > 0 {
+			if _, ok := b[0].([]any); ok {
+				cmds := make([]any, len(b))
+				for i, cmd := range b {
+					encoded, err := c.encodeArgs(cmd)
+					if err != nil {
+						return nil, err
+					}
+					cmds[i] = encoded
+				}
+				return cmds, nil
+			}
+		}
+		cmd := make([]any, len(b))
+		for i, arg := range b {
+			if _, ok := arg.(string); ok || i == 0 {
+				cmd[i] = arg
+				continue
+			}
+			token, err := c.argEncoder(arg)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d of %v: %w", i, b[0], err)
+			}
+			cmd[i] = token
+		}
+		return cmd, nil
+	case [][]any:
+		cmds := make([]any, len(b))
+		for i, cmd := range b {
+			encoded, err := c.encodeArgs(cmd)
+			if err != nil {
+				return nil, err
+			}
+			cmds[i] = encoded
+		}
+		return cmds, nil
+	default:
+		return body, nil
+	}
+}
+
+// commandName returns the upper-cased name of the command sent by a request, e.g. "HSET",
+// or "PIPELINE" and "MULTI-EXEC" for batches.
+func commandName(path []string, body any) string {
+	if len(path) > 0 {
+		return strings.ToUpper(path[0])
+	}
+	switch b := body.(type) {
+	case []any:
+		if len(b) > 0 {
+			return strings.ToUpper(fmt.Sprint(b[0]))
+		}
+	case []string:
+		if len(b) > 0 {
+			return strings.ToUpper(b[0])
+		}
+	}
+	return "UNKNOWN"
 }
 
 // Perform a request and return its response
@@ -99,22 +317,28 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 	start := time.Now()
 	if c.latencyLogger != nil {
 		defer func() {
-			cmd := "UNKNOWN"
-			if len(path) > 0 {
-				cmd = path[0]
-			} else if body != nil {
-				if b, ok := body.([]any); ok && len(b) > 0 {
-					cmd = fmt.Sprint(b[0])
-				}
-			}
-			c.latencyLogger(cmd, time.Since(start))
+			c.latencyLogger(commandName(path, body), time.Since(start))
 		}()
 	}
 
-	payload, err := marshalBody(body)
+	timeout := c.requestTimeout
+	if IsBlocking(body) {
+		timeout = c.blockingTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	rawBody, err := c.marshalBody(body)
 	if err != nil {
 		return nil, fmt.Errorf("unable to marshal request body: %w", err)
 	}
+	var payload io.Reader = nil
+	if rawBody != nil {
+		payload = bytes.NewBuffer(rawBody)
+	}
 
 	baseUrl := c.url
 	if method == "GET" && c.edgeUrl != "" {
@@ -136,6 +360,18 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 	if c.enableBase64 {
 		req.Header.Set("Upstash-Encoding", "base64")
 	}
+	if c.idempotencyKey {
+		// Generated once so that every retry of this request carries the same key.
+		req.Header.Set("Idempotency-Key", uuid.NewString())
+	}
+
+	if c.requestLogger != nil {
+		c.requestLogger(method, url, rawBody)
+	}
+
+	// A server error may come from a proxy after the command was applied, so it is only retried when
+	// replaying the request cannot apply a write twice.
+	retryServerErrors := method == "GET" || c.idempotencyKey || IsReadOnly(body)
 
 	var res *http.Response
 	var lastErr error
@@ -147,10 +383,26 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 			case <-ctx.Done():
 				return nil, ctx.Err()
 			}
+			// The previous attempt consumed the body, so rewind it.
+			if req.GetBody != nil {
+				req.Body, err = req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("unable to rewind request body: %w", err)
+				}
+			}
 		}
 
 		res, lastErr = c.httpClient.Do(req)
 		if lastErr == nil {
+			if c.inspector != nil {
+				c.inspector(res.Header)
+			}
+			// Server errors are transient, so they are retried too; the last response is handled below.
+			if res.StatusCode >= 500 && retryServerErrors && i < c.retries {
+				_, _ = io.Copy(io.Discard, res.Body)
+				_ = res.Body.Close()
+				continue
+			}
 			break
 		}
 		// If context is done, don't retry
@@ -169,20 +421,33 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 		var responseBody map[string]any
 		err = json.NewDecoder(res.Body).Decode(&responseBody)
 		if err != nil {
+			if res.StatusCode == http.StatusUnauthorized {
+				return nil, wrapError(fmt.Sprintf("response returned status code %d, path: %s", res.StatusCode, path), ErrAuth)
+			}
 			return nil, fmt.Errorf("unable to decode response body of bad response: %s: %w", res.Status, err)
 		}
 
+		errStr, _ := responseBody["error"].(string)
+		kind := replyKind(errStr)
+		if res.StatusCode == http.StatusUnauthorized {
+			kind = ErrAuth
+		}
+
 		// Try to prettyprint the response body
 		// If that is not possible we return the raw body
 		pretty, err := json.MarshalIndent(responseBody, "", "  ")
 		if err != nil {
-			return nil, fmt.Errorf("response returned status code %d: %+v, path: %s", res.StatusCode, responseBody, path)
+			return nil, wrapError(fmt.Sprintf("response returned status code %d: %+v, path: %s", res.StatusCode, responseBody, path), kind)
 		}
-		return nil, fmt.Errorf("response returned status code %d: %+v, path: %s", res.StatusCode, string(pretty), path)
+		return nil, wrapError(fmt.Sprintf("response returned status code %d: %+v, path: %s", res.StatusCode, string(pretty), path), kind)
 	}
 
 	var rawResponse any
-	err = json.NewDecoder(res.Body).Decode(&rawResponse)
+	decoder := json.NewDecoder(res.Body)
+	if c.useNumber {
+		decoder.UseNumber()
+	}
+	err = decoder.Decode(&rawResponse)
 	if err != nil {
 		return nil, fmt.Errorf("unable to unmarshal response: %w", err)
 	}
@@ -190,7 +455,7 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 	// Handle standard response: {"result": ...} or {"error": ...}
 	if respMap, ok := rawResponse.(map[string]any); ok {
 		if errStr, ok := respMap["error"].(string); ok && errStr != "" {
-			return nil, fmt.Errorf("%s", errStr)
+			return nil, CommandError(errStr)
 		}
 		if res, ok := respMap["result"]; ok {
 			if c.enableBase64 {
@@ -198,11 +463,9 @@ func (c *upstashClient) request(ctx context.Context, method string, path []strin
 			}
 			return res, nil
 		}
-		// If neither, return the map itself
-		if c.enableBase64 {
-			return decodeBase64(respMap), nil
-		}
-		return respMap, nil
+		// Neither result nor error: report the body rather than let command parsers trip over a map.
+		raw, _ := json.Marshal(respMap)
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, raw)
 	}
 
 	// Handle pipeline/transaction response: [{"result":...}, ...]
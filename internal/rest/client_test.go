@@ -13,7 +13,7 @@ import (
 )
 
 func TestNew(t *testing.T) {
-	c := rest.New("http://example.com", "http://edge.example.com", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New("http://example.com", "http://edge.example.com", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	require.NotNil(t, c)
 }
 
@@ -30,7 +30,7 @@ func TestRead(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{
 		Path: []string{"get", "foo"},
 	})
@@ -57,7 +57,7 @@ func TestWrite(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Write(context.Background(), rest.Request{
 		Path: []string{"set", "foo", "bar"},
 		Body: "body-content",
@@ -81,7 +81,7 @@ func TestEdgeUrl(t *testing.T) {
 	}))
 	defer restServer.Close()
 
-	c := rest.New(restServer.URL, edgeServer.URL, "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(restServer.URL, edgeServer.URL, "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{
 		Path: []string{"get", "foo"},
 	})
@@ -98,7 +98,7 @@ func TestApiError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Read(context.Background(), rest.Request{Path: []string{"get"}})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "ERR syntax error")
@@ -113,7 +113,7 @@ func TestServerError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Read(context.Background(), rest.Request{Path: []string{"get"}})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "response returned status code 500")
@@ -129,14 +129,14 @@ func TestResponseErrorField(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Read(context.Background(), rest.Request{Path: []string{"get"}})
 	require.Error(t, err)
 	require.Equal(t, "ERR logical error", err.Error())
 }
 
 func TestMarshalError(t *testing.T) {
-	c := rest.New("http://example.com", "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New("http://example.com", "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	// Pass a channel which cannot be marshaled to JSON
 	_, err := c.Write(context.Background(), rest.Request{
 		Body: make(chan int),
@@ -159,7 +159,7 @@ func TestBase64Decoding(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", true, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", true, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{})
 	require.NoError(t, err)
 
@@ -186,7 +186,7 @@ func TestRetries(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{})
 	require.NoError(t, err)
 	require.Equal(t, "success", res)
@@ -202,12 +202,116 @@ func TestRetryFailure(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Read(context.Background(), rest.Request{})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "unable to perform request after retries")
 }
 
+func TestRetriesServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "service unavailable"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "success"})
+	}))
+	defer server.Close()
+
+	var backoffs []int
+	backoff := func(i int) time.Duration {
+		backoffs = append(backoffs, i)
+		return time.Millisecond
+	}
+	c := rest.New(server.URL, "", "token", false, false, 3, backoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
+	res, err := c.Write(context.Background(), rest.Request{Body: []any{"GET", "k"}})
+	require.NoError(t, err)
+	require.Equal(t, "success", res)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []int{1, 2}, backoffs)
+}
+
+func TestRetriesServerErrorsOnlyWhenSafe(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "bad gateway"})
+	}))
+	defer server.Close()
+
+	noBackoff := func(int) time.Duration { return 0 }
+	for _, tc := range []struct {
+		name           string
+		idempotencyKey bool
+		body           any
+		attempts       int
+	}{
+		{"write", false, []any{"INCR", "k"}, 1},
+		{"write with idempotency key", true, []any{"INCR", "k"}, 3},
+		{"read-only pipeline", false, [][]any{{"GET", "a"}, {"ZSCORE", "z", "m"}}, 3},
+		{"pipeline with a write", false, [][]any{{"GET", "a"}, {"LPUSH", "l", "v"}}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts = 0
+			c := rest.New(server.URL, "", "token", false, false, 2, noBackoff, &http.Client{}, nil, 0, 0, tc.idempotencyKey, false, nil, nil, nil)
+			_, err := c.Write(context.Background(), rest.Request{Body: tc.body})
+			require.ErrorContains(t, err, "status code 502")
+			require.Equal(t, tc.attempts, attempts)
+		})
+	}
+}
+
+func TestNoRetryOnClientErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "ERR syntax error"})
+	}))
+	defer server.Close()
+
+	c := rest.New(server.URL, "", "token", false, false, 3, func(int) time.Duration { return time.Millisecond }, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
+	_, err := c.Write(context.Background(), rest.Request{Body: []any{"GET"}})
+	require.ErrorContains(t, err, "ERR syntax error")
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetriesServerErrorsExhausted(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "bad gateway"})
+	}))
+	defer server.Close()
+
+	c := rest.New(server.URL, "", "token", false, false, 2, func(int) time.Duration { return time.Millisecond }, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
+	_, err := c.Write(context.Background(), rest.Request{Body: []any{"GET", "k"}})
+	require.ErrorContains(t, err, "status code 502")
+	require.Equal(t, 3, attempts)
+}
+
+func TestContextDeadlineDuringServerErrorBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "service unavailable"})
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := rest.New(server.URL, "", "token", false, false, 3, func(int) time.Duration { return time.Minute }, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
+	start := time.Now()
+	_, err := c.Write(ctx, rest.Request{Body: []any{"GET", "k"}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
 func TestContextCancelledDuringRetry(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		hj, _ := w.(http.Hijacker)
@@ -219,7 +323,7 @@ func TestContextCancelledDuringRetry(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // cancel immediately
 
-	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Read(ctx, rest.Request{})
 	require.Error(t, err)
 	require.Equal(t, context.Canceled, err)
@@ -239,13 +343,64 @@ func TestLatencyLogger(t *testing.T) {
 		loggedLatency = latency
 	}
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, logger)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, logger, 0, 0, false, false, nil, nil, nil)
 	_, _ = c.Read(context.Background(), rest.Request{Path: []string{"GET"}})
 
 	require.Equal(t, "GET", loggedCmd)
 	require.True(t, loggedLatency > 0)
 }
 
+func TestLatencyLoggerCommandNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pipeline" || r.URL.Path == "/multi-exec" {
+			_ = json.NewEncoder(w).Encode([]any{map[string]any{"result": "OK"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "ok"})
+	}))
+	defer server.Close()
+
+	var logged []string
+	logger := func(cmd string, latency time.Duration) {
+		require.Positive(t, latency)
+		logged = append(logged, cmd)
+	}
+
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, logger, 0, 0, false, false, nil, nil, nil)
+	ctx := context.Background()
+	_, _ = c.Write(ctx, rest.Request{Body: []string{"hset", "h", "f", "v"}})
+	_, _ = c.Write(ctx, rest.Request{Body: []any{"HGET", "h", "f"}})
+	_, _ = c.Read(ctx, rest.Request{Path: []string{"get", "k"}})
+	_, _ = c.Write(ctx, rest.Request{Path: []string{"pipeline"}, Body: [][]any{{"SET", "k", "v"}}})
+	_, _ = c.Write(ctx, rest.Request{Path: []string{"multi-exec"}, Body: [][]any{{"SET", "k", "v"}}})
+
+	require.Equal(t, []string{"HSET", "HGET", "GET", "PIPELINE", "MULTI-EXEC"}, logged)
+}
+
+func TestLatencyLoggerIncludesRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "unavailable"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "ok"})
+	}))
+	defer server.Close()
+
+	var loggedLatency time.Duration
+	logger := func(cmd string, latency time.Duration) { loggedLatency = latency }
+	backoff := func(int) time.Duration { return 20 * time.Millisecond }
+
+	c := rest.New(server.URL, "", "token", false, false, 1, backoff, &http.Client{}, logger, 0, 0, false, false, nil, nil, nil)
+	_, err := c.Write(context.Background(), rest.Request{Body: []any{"GET", "k"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.GreaterOrEqual(t, loggedLatency, 20*time.Millisecond)
+}
+
 func TestDefaultBackoff(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		d := rest.DefaultBackoff(i)
@@ -269,7 +424,7 @@ func TestBase64DecodingNested(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", true, true, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", true, true, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{})
 	require.NoError(t, err)
 
@@ -284,7 +439,7 @@ func TestStreamErrors(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, true, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, true, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	_, err := c.Stream(context.Background(), rest.Request{})
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "stream request returned status code 500")
@@ -297,7 +452,7 @@ func TestRawResponseBranches(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", true, true, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", true, true, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	res, err := c.Read(context.Background(), rest.Request{})
 	require.NoError(t, err)
 	require.Equal(t, "raw-string", res)
@@ -311,7 +466,7 @@ func TestStream(t *testing.T) {
 	}))
 	defer server.Close()
 
-	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil)
+	c := rest.New(server.URL, "", "token", false, false, 0, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, false, false, nil, nil, nil)
 	stream, err := c.Stream(context.Background(), rest.Request{Path: []string{"sub"}})
 	require.NoError(t, err)
 	require.NotNil(t, stream)
@@ -321,3 +476,32 @@ func TestStream(t *testing.T) {
 	require.Contains(t, string(buf[:n]), "data: hello")
 	_ = stream.Close()
 }
+
+func TestIdempotencyKey(t *testing.T) {
+	attempts := 0
+	keys := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		var body any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		require.Equal(t, []any{"INCR", "k"}, body)
+		if attempts < 2 {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": 1})
+	}))
+	defer server.Close()
+
+	c := rest.New(server.URL, "", "token", false, false, 3, rest.DefaultBackoff, &http.Client{}, nil, 0, 0, true, false, nil, nil, nil)
+	res, err := c.Write(context.Background(), rest.Request{Body: []any{"INCR", "k"}})
+	require.NoError(t, err)
+	require.Equal(t, float64(1), res)
+	require.Equal(t, 2, attempts)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrWrongType is wrapped by errors caused by a WRONGTYPE reply, i.e. an operation against a key
+// holding the wrong kind of value.
+var ErrWrongType = errors.New("WRONGTYPE")
+
+// ErrNotBusy is wrapped by errors caused by a NOTBUSY reply, returned by SCRIPT KILL and
+// FUNCTION KILL when no script or function is running.
+var ErrNotBusy = errors.New("NOTBUSY")
+
+// ErrNoPerm is wrapped by errors caused by a NOPERM reply, i.e. a command the user is not allowed to run.
+var ErrNoPerm = errors.New("NOPERM")
+
+// ErrAuth is wrapped by errors caused by failed authentication: WRONGPASS and NOAUTH replies,
+// and responses with status 401 Unauthorized.
+var ErrAuth = errors.New("authentication failed")
+
+// ErrUnexpectedResponse is wrapped by errors caused by a JSON response object that has neither
+// a result nor an error. The error message includes the raw body.
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// commandError is an error reply that wraps a sentinel identifying its kind, keeping the original message.
+type commandError struct {
+	msg  string
+	kind error
+}
+
+func (e *commandError) Error() string {
+	return e.msg
+}
+
+func (e *commandError) Unwrap() error {
+	return e.kind
+}
+
+// CommandError converts the error message errStr returned by the server into an error.
+// Replies starting with WRONGTYPE, NOTBUSY or NOPERM wrap ErrWrongType, ErrNotBusy or ErrNoPerm respectively,
+// and WRONGPASS or NOAUTH replies wrap ErrAuth.
+func CommandError(errStr string) error {
+	return wrapError(errStr, replyKind(errStr))
+}
+
+// replyKind returns the sentinel matching the server reply errStr, or nil if there is none.
+func replyKind(errStr string) error {
+	switch {
+	case strings.HasPrefix(errStr, "WRONGTYPE"):
+		return ErrWrongType
+	case strings.HasPrefix(errStr, "NOTBUSY"):
+		return ErrNotBusy
+	case strings.HasPrefix(errStr, "NOPERM"):
+		return ErrNoPerm
+	case strings.HasPrefix(errStr, "WRONGPASS"), strings.HasPrefix(errStr, "NOAUTH"):
+		return ErrAuth
+	}
+	return nil
+}
+
+// wrapError returns an error with message msg, wrapping kind if it is not nil.
+func wrapError(msg string, kind error) error {
+	if kind == nil {
+		return errors.New(msg)
+	}
+	return &commandError{msg: msg, kind: kind}
+}
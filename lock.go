@@ -0,0 +1,49 @@
+package upstash
+
+import (
+	"context"
+	"time"
+)
+
+const releaseLockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0`
+
+const extendLockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+
+// AcquireLock tries to acquire a lock on key, held by token, that expires after ttl.
+// It returns false if the lock is already held.
+// token should be unique per holder so that only the holder can release or extend the lock.
+func (u *Upstash) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	res, err := u.Send(ctx, "SET", key, token, "NX", "PX", ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	return res != nil, nil
+}
+
+// ReleaseLock releases the lock on key if it is still held by token.
+// It returns false if the lock expired or is held by someone else.
+func (u *Upstash) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	res, err := u.EvalCached(ctx, releaseLockScript, []string{key}, token)
+	if err != nil {
+		return false, err
+	}
+	n, err := asInt64("EVALSHA", res)
+	return n == 1, err
+}
+
+// ExtendLock resets the expiry of the lock on key to ttl if it is still held by token.
+// It returns false if the lock expired or is held by someone else.
+func (u *Upstash) ExtendLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	res, err := u.EvalCached(ctx, extendLockScript, []string{key}, token, ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := asInt64("EVALSHA", res)
+	return n == 1, err
+}
@@ -0,0 +1,91 @@
+package upstash
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+const fixedWindowScript = `local current = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[1])
+  ttl = tonumber(ARGV[1])
+end
+return {current, ttl}`
+
+const slidingWindowScript = `local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call("ZADD", KEYS[1], now, ARGV[3])
+  count = count + 1
+  allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+local reset = 0
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+  reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}`
+
+// RateLimit applies a fixed-window rate limit to key, allowing at most limit requests per window.
+// The counter is incremented and given an expiry on the first hit atomically in a single script.
+// resetAfter is the time left until the current window ends.
+func (u *Upstash) RateLimit(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, remaining int, resetAfter time.Duration, err error) {
+	res, err := u.EvalCached(ctx, fixedWindowScript, []string{key}, window.Milliseconds())
+	if err != nil {
+		return false, 0, 0, err
+	}
+	list, ok := res.([]any)
+	if !ok || len(list) != 2 {
+		return false, 0, 0, fmt.Errorf("unexpected return type for rate limit: %T", res)
+	}
+	current, err := asInt("EVALSHA", list[0])
+	if err != nil {
+		return false, 0, 0, err
+	}
+	ttl, err := asInt64("EVALSHA", list[1])
+	if err != nil {
+		return false, 0, 0, err
+	}
+	resetAfter = time.Duration(ttl) * time.Millisecond
+	return current <= limit, max(limit-current, 0), resetAfter, nil
+}
+
+// SlidingWindowRateLimit applies a sliding-window rate limit to key, allowing at most limit requests
+// in any period of length window. Each allowed request is recorded as a timestamp in a sorted set,
+// and timestamps older than the window are discarded atomically in a single script.
+// resetAfter is the time left until the oldest recorded request leaves the window.
+func (u *Upstash) SlidingWindowRateLimit(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, remaining int, resetAfter time.Duration, err error) {
+	member := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+	res, err := u.EvalCached(ctx, slidingWindowScript, []string{key}, window.Milliseconds(), limit, member)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	list, ok := res.([]any)
+	if !ok || len(list) != 3 {
+		return false, 0, 0, fmt.Errorf("unexpected return type for sliding window rate limit: %T", res)
+	}
+	admitted, err := asInt("EVALSHA", list[0])
+	if err != nil {
+		return false, 0, 0, err
+	}
+	count, err := asInt("EVALSHA", list[1])
+	if err != nil {
+		return false, 0, 0, err
+	}
+	ttl, err := asInt64("EVALSHA", list[2])
+	if err != nil {
+		return false, 0, 0, err
+	}
+	resetAfter = time.Duration(ttl) * time.Millisecond
+	return admitted == 1, max(limit-count, 0), resetAfter, nil
+}
@@ -7,6 +7,7 @@ type KV struct {
 }
 
 // SetOptions represents options for the SET command.
+// At most one of EX, PX, EXAT, PXAT and KEEPTTL may be set.
 type SetOptions struct {
 	// EX sets the specified expire time, in seconds.
 	EX int
@@ -14,11 +15,24 @@ type SetOptions struct {
 	// PX sets the specified expire time, in milliseconds.
 	PX int
 
+	// EXAT sets the specified Unix time at which the key will expire, in seconds.
+	EXAT int
+
+	// PXAT sets the specified Unix time at which the key will expire, in milliseconds.
+	PXAT int
+
+	// KEEPTTL retains the time to live associated with the key.
+	KEEPTTL bool
+
 	// NX only sets the key if it does not already exist.
 	NX bool
 
 	// XX only sets the key if it already exists.
 	XX bool
+
+	// GET returns the value previously stored at key. Use SetGetOld to read it;
+	// SetWithOptions discards it.
+	GET bool
 }
 
 // GetEXOptions represents options for the GETEX command.
@@ -56,6 +70,12 @@ type ScanResult struct {
 	Items  []string
 }
 
+// ZScanResult represents the result of a ZSCAN command with its member/score pairs parsed.
+type ZScanResult struct {
+	Cursor  string
+	Members []ZMember
+}
+
 // GeoLocation represents a longitude and latitude pair.
 type GeoLocation struct {
 	Longitude float64
@@ -67,6 +87,11 @@ type GeoLocation struct {
 type StreamMessage struct {
 	ID     string
 	Values map[string]string
+
+	// ValuesOrdered holds the entry's field-value pairs in the order they were added.
+	// Redis allows a field name to appear more than once in a single entry; Values keeps
+	// only the last occurrence, whereas ValuesOrdered preserves every pair.
+	ValuesOrdered []KV
 }
 
 // XReadGroupOptions represents options for the XREADGROUP command.
@@ -77,3 +102,328 @@ type XReadGroupOptions struct {
 	Block    int
 	NoAck    bool
 }
+
+// KeyType represents the type of the value stored at a key, as reported by TYPE.
+type KeyType string
+
+const (
+	KeyTypeNone   KeyType = "none"
+	KeyTypeString KeyType = "string"
+	KeyTypeList   KeyType = "list"
+	KeyTypeSet    KeyType = "set"
+	KeyTypeZSet   KeyType = "zset"
+	KeyTypeHash   KeyType = "hash"
+	KeyTypeStream KeyType = "stream"
+	KeyTypeJSON   KeyType = "ReJSON-RL"
+)
+
+// GeoAddOptions represents options for the GEOADD command.
+// Unlike ZADD, GEOADD does not support GT or LT.
+type GeoAddOptions struct {
+	// NX only adds new members and never updates existing ones.
+	NX bool
+
+	// XX only updates existing members and never adds new ones.
+	XX bool
+
+	// CH changes the return value from the number of new members added
+	// to the number of members changed (added or whose position was updated).
+	// Members re-added with an identical position are not counted.
+	CH bool
+
+	// TrackChanges makes GeoAddWithResult report which members were added or moved,
+	// by reading their positions before and after the GEOADD in the same transaction.
+	TrackChanges bool
+}
+
+// GeoAddResult represents the result of GeoAddWithResult.
+type GeoAddResult struct {
+	// Count is the reply of GEOADD: the number of members added, or changed if CH is set.
+	Count int
+
+	// Changed lists the members that were added or whose position changed, in the order given.
+	// It is only populated when TrackChanges is set.
+	Changed []string
+}
+
+// ZMember represents a sorted set member with its score.
+type ZMember struct {
+	Score  float64
+	Member string
+}
+
+// ZAddOptions represents the condition flags of a ZADD command.
+type ZAddOptions struct {
+	// NX only adds new members and never updates existing ones.
+	NX bool
+
+	// XX only updates existing members and never adds new ones.
+	XX bool
+
+	// GT only updates existing members if the new score is greater than the current one.
+	GT bool
+
+	// LT only updates existing members if the new score is less than the current one.
+	LT bool
+}
+
+// ZAddArgs represents the flags and members of a ZADD command.
+type ZAddArgs struct {
+	// NX only adds new members and never updates existing ones.
+	NX bool
+
+	// XX only updates existing members and never adds new ones.
+	XX bool
+
+	// GT only updates existing members if the new score is greater than the current one.
+	GT bool
+
+	// LT only updates existing members if the new score is less than the current one.
+	LT bool
+
+	// CH counts changed members, not only added ones, in the reply.
+	CH bool
+
+	// INCR increments the score of the single member by its Score, like ZINCRBY.
+	INCR bool
+
+	Members []ZMember
+}
+
+// ZMemberString represents a sorted set member with its score as the exact string stored by Redis.
+type ZMemberString struct {
+	Member string
+	Score  string
+}
+
+// RestoreOptions represents options for the RESTORE command.
+type RestoreOptions struct {
+	// Replace overwrites the key if it already exists.
+	Replace bool
+
+	// AbsTTL interprets the ttl as an absolute Unix timestamp in milliseconds.
+	AbsTTL bool
+
+	// IdleTime sets the object idle time, in seconds. Requires an LRU maxmemory-policy.
+	IdleTime *int
+
+	// Freq sets the object access frequency counter. Requires an LFU maxmemory-policy.
+	Freq *int
+}
+
+// XPendingEntry represents an entry in the pending entries list of a consumer group.
+type XPendingEntry struct {
+	ID            string
+	Consumer      string
+	DeliveryTime  int64
+	DeliveryCount int64
+}
+
+// XStreamConsumerFull represents a consumer as reported by XINFO STREAM FULL.
+type XStreamConsumerFull struct {
+	Name       string
+	SeenTime   int64
+	ActiveTime int64
+	PelCount   int64
+	Pending    []XPendingEntry
+}
+
+// XStreamGroupFull represents a consumer group as reported by XINFO STREAM FULL.
+type XStreamGroupFull struct {
+	Name            string
+	LastDeliveredID string
+	EntriesRead     int64
+	Lag             int64
+	PelCount        int64
+	Pending         []XPendingEntry
+	Consumers       []XStreamConsumerFull
+}
+
+// XConsumerInfo represents a consumer in the reply of XINFO CONSUMERS.
+type XConsumerInfo struct {
+	Name    string
+	Pending int64
+
+	// Idle is the number of milliseconds since the consumer last attempted an interaction.
+	Idle int64
+
+	// Inactive is the number of milliseconds since the consumer last successfully read or claimed
+	// an entry, or -1 if it never did. It is nil on servers older than Redis 7.2.
+	Inactive *int64
+}
+
+// XGroupInfo represents a consumer group in the reply of XINFO GROUPS.
+type XGroupInfo struct {
+	Name            string
+	Consumers       int64
+	Pending         int64
+	LastDeliveredID string
+
+	// EntriesRead is the logical read counter of the group. It is nil on servers older than Redis 7.
+	EntriesRead *int64
+
+	// Lag is the number of entries not yet delivered to the group. It is nil on servers older than
+	// Redis 7, and also when the server cannot compute it, e.g. after entries were deleted.
+	Lag *int64
+}
+
+// XStreamFullInfo represents the reply of XINFO STREAM FULL.
+type XStreamFullInfo struct {
+	Length               int64
+	LastGeneratedID      string
+	MaxDeletedEntryID    string
+	EntriesAdded         int64
+	RecordedFirstEntryID string
+	Entries              []StreamMessage
+	Groups               []XStreamGroupFull
+	// Raw holds every field of the reply, including those not parsed above.
+	Raw map[string]any
+}
+
+// ZRangeLimit limits the elements returned by a BYSCORE or BYLEX range.
+type ZRangeLimit struct {
+	Offset int
+	Count  int
+}
+
+// ZRangeOptions represents options for the ZRANGE command.
+type ZRangeOptions struct {
+	// ByScore interprets start and stop as scores, e.g. 1, "(5" or "+inf".
+	ByScore bool
+
+	// ByLex interprets start and stop as lexicographical bounds, e.g. "[a" or "-".
+	ByLex bool
+
+	// Rev reverses the ordering. With ByScore or ByLex, start must be the higher bound.
+	Rev bool
+
+	// Limit returns a slice of the matching elements. Only valid with ByScore or ByLex.
+	Limit *ZRangeLimit
+
+	// WithScores interleaves each member with its score in the result.
+	WithScores bool
+}
+
+// GeoSearchOptions represents options for the GEOSEARCH and GEOSEARCHSTORE commands.
+// Exactly one of FromMember or FromLonLat, and one of ByRadius or ByBox, should be set.
+type GeoSearchOptions struct {
+	// FromMember uses the position of an existing member as the center.
+	FromMember string
+
+	// FromLonLat uses the given longitude and latitude as the center.
+	FromLonLat *[2]float64
+
+	// ByRadius searches within a circle of the given radius.
+	ByRadius float64
+
+	// ByBox searches within an axis-aligned rectangle of the given width and height.
+	ByBox *[2]float64
+
+	// Unit is the unit of the radius or box dimensions: m, km, ft or mi. Defaults to m.
+	Unit string
+
+	// Sort orders the results by distance: ASC or DESC.
+	Sort string
+
+	// Count limits the number of results.
+	Count int
+
+	// Any returns as soon as Count matches are found, which may not be the closest ones.
+	Any bool
+}
+
+// ClientKillOptions represents the filters of the CLIENT KILL command.
+// At least one filter should be set; connections matching all of them are closed.
+type ClientKillOptions struct {
+	// ID closes the connection with the given client ID.
+	ID int64
+
+	// Addr closes the connections from the given ip:port.
+	Addr string
+
+	// LAddr closes the connections to the given local ip:port.
+	LAddr string
+
+	// User closes the connections authenticated as the given user.
+	User string
+
+	// Type closes the connections of the given type: normal, master, replica or pubsub.
+	Type string
+}
+
+// XAckDelOptions represents the reference-handling condition of the XACKDEL and XDELEX commands.
+// At most one of these should be set; by default the server uses KEEPREF.
+type XAckDelOptions struct {
+	// KeepRef deletes the entries but keeps their references in the pending entries lists of other groups.
+	KeepRef bool
+
+	// DelRef deletes the entries and removes their references from all pending entries lists.
+	DelRef bool
+
+	// Acked only deletes the entries that were acknowledged by all consumer groups.
+	Acked bool
+}
+
+// LatencySample represents an entry in the reply of LATENCY HISTORY.
+type LatencySample struct {
+	// Timestamp is the Unix time, in seconds, at which the spike was recorded.
+	Timestamp int64
+
+	// Latency is the duration of the spike, in milliseconds.
+	Latency int64
+}
+
+// LatencyEvent represents an entry in the reply of LATENCY LATEST.
+type LatencyEvent struct {
+	Name string
+
+	// Timestamp is the Unix time, in seconds, of the latest spike.
+	Timestamp int64
+
+	// Latest is the duration of the latest spike, in milliseconds.
+	Latest int64
+
+	// Max is the all-time maximum duration of the event, in milliseconds.
+	Max int64
+}
+
+// WaitResult represents the outcome of WaitDetailed.
+type WaitResult struct {
+	// Acknowledged is the number of replicas that acknowledged the previous write commands.
+	Acknowledged int
+
+	// TimedOut reports whether fewer replicas than requested acknowledged them in time.
+	TimedOut bool
+}
+
+// JsonSetEntry represents a JSON value to set at Path in the document stored at Key, as used by JsonMSet.
+type JsonSetEntry struct {
+	Key   string
+	Path  string
+	Value any
+}
+
+// SortLimit limits the elements returned by SORT.
+type SortLimit struct {
+	Offset int
+	Count  int
+}
+
+// SortOptions represents options for the SORT command.
+type SortOptions struct {
+	// By sorts by the values of external keys matching the pattern, e.g. "weight_*", instead of the elements.
+	By string
+
+	// Limit returns a slice of the sorted elements.
+	Limit *SortLimit
+
+	// Get returns the values of external keys matching each pattern instead of the elements.
+	// "#" returns the element itself.
+	Get []string
+
+	// Desc sorts in descending order.
+	Desc bool
+
+	// Alpha sorts lexicographically instead of numerically.
+	Alpha bool
+}
@@ -2,16 +2,29 @@ package upstash_test
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/claywarren/upstash-go"
 	"github.com/stretchr/testify/require"
 )
 
+// noBackoff retries failed requests immediately, so tests of server errors do not wait between attempts.
+func noBackoff(int) time.Duration { return 0 }
+
 type mockHandler struct {
 	method       string
 	path         string // Optional: check path
@@ -72,6 +85,22 @@ func setupMockServer(t *testing.T, handlers []mockHandler) (*upstash.Upstash, fu
 	return &u, server.Close
 }
 
+func TestUnitNewMissingCredentials(t *testing.T) {
+	t.Setenv("UPSTASH_REDIS_REST_URL", "")
+	t.Setenv("UPSTASH_REDIS_REST_TOKEN", "")
+
+	_, err := upstash.New(upstash.Options{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing url")
+
+	_, err = upstash.New(upstash.Options{Url: "http://localhost"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing token")
+
+	_, err = upstash.New(upstash.Options{SkipCredentialsCheck: true})
+	require.NoError(t, err)
+}
+
 func TestUnitSend(t *testing.T) {
 	u, close := setupMockServer(t, []mockHandler{
 		{
@@ -88,6 +117,25 @@ func TestUnitSend(t *testing.T) {
 	require.Equal(t, 1.0, val)
 }
 
+func TestUnitSendRaw(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"HSET", "myhash", "field1", "value1"},
+			response:     float64(1),
+			status:       200,
+		},
+	})
+	defer close()
+
+	val, err := u.SendRaw(context.Background(), []any{"HSET", "myhash", "field1", "value1"})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, val)
+
+	_, err = u.SendRaw(context.Background(), nil)
+	require.Error(t, err)
+}
+
 func TestUnitPipeline(t *testing.T) {
 	u, close := setupMockServer(t, []mockHandler{
 		{
@@ -517,7 +565,7 @@ func TestUnitClientErrors(t *testing.T) {
 	}))
 	defer server.Close()
 
-	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", Retry: upstash.RetryConfig{Backoff: noBackoff}})
 	ctx := context.Background()
 
 	t.Run("Append", func(t *testing.T) { _, err := u.Append(ctx, "k", "v"); require.Error(t, err) })
@@ -544,7 +592,7 @@ func TestUnitSetWithOptionsErrors(t *testing.T) {
 	}))
 	defer server.Close()
 
-	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", Retry: upstash.RetryConfig{Backoff: noBackoff}})
 
 	err := u.SetWithOptions(context.Background(), "k", "v", upstash.SetOptions{EX: 10})
 	require.Error(t, err)
@@ -557,7 +605,7 @@ func TestUnitKeysError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", Retry: upstash.RetryConfig{Backoff: noBackoff}})
 	_, err := u.Keys(context.Background(), "*")
 	require.Error(t, err)
 }
@@ -1287,6 +1335,12 @@ func TestUnitConnectionMethods(t *testing.T) {
 			response:     "hello",
 			status:       200,
 		},
+		{
+			method:       "POST",
+			expectedBody: []any{"RESET"},
+			response:     "RESET",
+			status:       200,
+		},
 	})
 	defer close()
 
@@ -1298,6 +1352,10 @@ func TestUnitConnectionMethods(t *testing.T) {
 	res, err = u.Echo(ctx, "hello")
 	require.NoError(t, err)
 	require.Equal(t, "hello", res)
+
+	res, err = u.Reset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "RESET", res)
 }
 
 func TestUnitGenericMethods(t *testing.T) {
@@ -1486,6 +1544,8 @@ func TestUnitSubscribe(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		flusher, _ := w.(http.Flusher)
 
+		_, _ = fmt.Fprint(w, "data: subscribe,ch,1\n\n")
+		flusher.Flush()
 		_, _ = fmt.Fprint(w, "data: \"hello\"\n\n")
 		flusher.Flush()
 		_, _ = fmt.Fprint(w, "data: world\n\n")
@@ -1504,3 +1564,3471 @@ func TestUnitSubscribe(t *testing.T) {
 	require.Equal(t, "hello", <-msgs)
 	require.Equal(t, "world", <-msgs)
 }
+
+func TestUnitBlockingPopOK(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"BLPOP", "l", float64(1)},
+			response:     []any{"l", "v"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"BRPOP", "l", float64(1)},
+			response:     nil,
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"BZPOPMAX", "zs", float64(1)},
+			response:     []any{"zs", "m", "2.5"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"BZPOPMIN", "zs", float64(1)},
+			response:     nil,
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	key, value, ok, err := u.BLPopOK(ctx, 1, "l")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "l", key)
+	require.Equal(t, "v", value)
+
+	key, value, ok, err = u.BRPopOK(ctx, 1, "l")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Empty(t, key)
+	require.Empty(t, value)
+
+	key, member, score, ok, err := u.BZPopMaxOK(ctx, 1, "zs")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "zs", key)
+	require.Equal(t, "m", member)
+	require.Equal(t, 2.5, score)
+
+	_, _, _, ok, err = u.BZPopMinOK(ctx, 1, "zs")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestUnitCopyIfType(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"TYPE", "src"},
+			response:     "hash",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"COPY", "src", "dst", "REPLACE"},
+			response:     float64(1),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"TYPE", "src"},
+			response:     "string",
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.CopyIfType(ctx, "src", "dst", upstash.KeyTypeHash, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, res)
+
+	_, err = u.CopyIfType(ctx, "src", "dst", upstash.KeyTypeHash, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "has type string, expected hash")
+}
+
+func TestUnitEvalInto(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"EVAL", "return {KEYS[1], ARGV[1], 3}", float64(1), "k", "v"},
+			response:     []any{"k", "v", float64(3)},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"EVAL", "return {1, 2}", float64(0)},
+			response:     []any{float64(1), float64(2)},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	var mixed []any
+	err := u.EvalInto(ctx, &mixed, "return {KEYS[1], ARGV[1], 3}", []string{"k"}, "v")
+	require.NoError(t, err)
+	require.Equal(t, []any{"k", "v", float64(3)}, mixed)
+
+	var ints []int
+	err = u.EvalInto(ctx, &ints, "return {1, 2}", nil)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, ints)
+}
+
+func TestUnitGeoAddWithOptions(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"GEOADD", "g", "XX", "CH", 13.361389, 38.115556, "Palermo", 15.087269, 37.502669, "Catania"},
+			// Only Palermo moved; Catania was re-added at the same position.
+			response: float64(1),
+			status:   200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"GEOADD", "g", "XX", 15.087269, 37.502669, "Catania"},
+			// Without CH, updates of existing members are never counted.
+			response: float64(0),
+			status:   200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	changed, err := u.GeoAddWithOptions(ctx, "g", upstash.GeoAddOptions{XX: true, CH: true},
+		upstash.GeoLocation{Longitude: 13.361389, Latitude: 38.115556, Member: "Palermo"},
+		upstash.GeoLocation{Longitude: 15.087269, Latitude: 37.502669, Member: "Catania"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, changed)
+
+	added, err := u.GeoAddWithOptions(ctx, "g", upstash.GeoAddOptions{XX: true},
+		upstash.GeoLocation{Longitude: 15.087269, Latitude: 37.502669, Member: "Catania"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+}
+
+func TestUnitSetAndGetTTL(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"set", "k", "v", "ex", "60", "nx"},
+				[]any{"ttl", "k"},
+			},
+			response: []any{
+				map[string]any{"result": "OK"},
+				map[string]any{"result": float64(60)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"set", "k", "v", "ex", "60", "nx"},
+				[]any{"ttl", "k"},
+			},
+			response: []any{
+				map[string]any{"result": nil},
+				map[string]any{"result": float64(42)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+	opts := upstash.SetOptions{EX: 60, NX: true}
+
+	applied, ttl, err := u.SetAndGetTTL(ctx, "k", "v", opts)
+	require.NoError(t, err)
+	require.True(t, applied)
+	require.Equal(t, 60, ttl)
+
+	applied, ttl, err = u.SetAndGetTTL(ctx, "k", "v", opts)
+	require.NoError(t, err)
+	require.False(t, applied)
+	require.Equal(t, 42, ttl)
+}
+
+func TestUnitEvalCached(t *testing.T) {
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+		switch step {
+		case 0:
+			require.Equal(t, []any{"EVALSHA", "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", float64(0)}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "NOSCRIPT No matching script."})
+		case 1:
+			require.Equal(t, []any{"EVAL", "return 1", float64(0)}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": float64(1)})
+		}
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+
+	res, err := u.EvalCached(context.Background(), "return 1", nil)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), res)
+	require.Equal(t, 2, step)
+}
+
+func TestUnitRateLimit(t *testing.T) {
+	replies := []any{
+		[]any{float64(3), float64(400)},
+		[]any{float64(6), float64(100)},
+	}
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		require.Equal(t, "EVALSHA", body[0])
+		require.Equal(t, []any{float64(1), "rl", float64(1000)}, body[2:])
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": replies[step]})
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	allowed, remaining, resetAfter, err := u.RateLimit(ctx, "rl", 5, time.Second)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, 2, remaining)
+	require.Equal(t, 400*time.Millisecond, resetAfter)
+
+	allowed, remaining, resetAfter, err = u.RateLimit(ctx, "rl", 5, time.Second)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, 0, remaining)
+	require.Equal(t, 100*time.Millisecond, resetAfter)
+}
+
+func TestUnitSlidingWindowRateLimit(t *testing.T) {
+	replies := []any{
+		[]any{float64(1), float64(2), float64(800)},
+		[]any{float64(0), float64(3), float64(250)},
+	}
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		require.Equal(t, "EVALSHA", body[0])
+		require.Equal(t, []any{float64(1), "swrl", float64(1000), float64(3)}, body[2:6])
+		require.NotEmpty(t, body[6])
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": replies[step]})
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	allowed, remaining, resetAfter, err := u.SlidingWindowRateLimit(ctx, "swrl", 3, time.Second)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, 1, remaining)
+	require.Equal(t, 800*time.Millisecond, resetAfter)
+
+	allowed, remaining, resetAfter, err = u.SlidingWindowRateLimit(ctx, "swrl", 3, time.Second)
+	require.NoError(t, err)
+	require.False(t, allowed)
+	require.Equal(t, 0, remaining)
+	require.Equal(t, 250*time.Millisecond, resetAfter)
+}
+
+func TestUnitAcquireLock(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"SET", "lock", "owner-a", "NX", "PX", float64(5000)},
+			response:     "OK",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"SET", "lock", "owner-b", "NX", "PX", float64(5000)},
+			response:     nil,
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	acquired, err := u.AcquireLock(ctx, "lock", "owner-a", 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	acquired, err = u.AcquireLock(ctx, "lock", "owner-b", 5*time.Second)
+	require.NoError(t, err)
+	require.False(t, acquired)
+}
+
+func TestUnitReleaseAndExtendLock(t *testing.T) {
+	type call struct {
+		args  []any
+		reply float64
+	}
+	calls := []call{
+		{args: []any{float64(1), "lock", "owner-b"}, reply: 0},
+		{args: []any{float64(1), "lock", "owner-a", float64(10000)}, reply: 1},
+		{args: []any{float64(1), "lock", "owner-a"}, reply: 1},
+	}
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		require.Equal(t, "EVALSHA", body[0])
+		require.Equal(t, calls[step].args, body[2:])
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": calls[step].reply})
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	released, err := u.ReleaseLock(ctx, "lock", "owner-b")
+	require.NoError(t, err)
+	require.False(t, released)
+
+	extended, err := u.ExtendLock(ctx, "lock", "owner-a", 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, extended)
+
+	released, err = u.ReleaseLock(ctx, "lock", "owner-a")
+	require.NoError(t, err)
+	require.True(t, released)
+}
+
+func TestUnitAccessFrequency(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"OBJECT", "FREQ", "k"},
+			response:     float64(7),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"OBJECT", "FREQ", "k"},
+			response: map[string]any{
+				"error": "ERR An LFU maxmemory policy is not selected, access frequency not tracked.",
+			},
+			rawResponse: true,
+			status:      400,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	freq, err := u.AccessFrequency(ctx, "k")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), freq)
+
+	_, err = u.AccessFrequency(ctx, "k")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "object freq requires an LFU maxmemory-policy")
+}
+
+func TestUnitZScoreString(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"ZSCORE", "zs", "m1"},
+			response:     "9007199254740993",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"ZSCORE", "zs", "missing"},
+			response:     nil,
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"ZRANGE", "zs", float64(0), float64(-1), "WITHSCORES"},
+			response:     []any{"m0", "1", "m1", "9007199254740993"},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	score, ok, err := u.ZScoreString(ctx, "zs", "m1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "9007199254740993", score)
+
+	_, ok, err = u.ZScoreString(ctx, "zs", "missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	members, err := u.ZRangeWithScoresString(ctx, "zs", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.ZMemberString{
+		{Member: "m0", Score: "1"},
+		{Member: "m1", Score: "9007199254740993"},
+	}, members)
+}
+
+func TestUnitIncrChecked(t *testing.T) {
+	notInteger := map[string]any{"error": "ERR value is not an integer or out of range"}
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"incr", "k"},
+			response:     float64(2),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"incr", "k"},
+			response:     notInteger,
+			rawResponse:  true,
+			status:       400,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"incrby", "k", "5"},
+			response:     notInteger,
+			rawResponse:  true,
+			status:       400,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"decrby", "k", "5"},
+			response:     notInteger,
+			rawResponse:  true,
+			status:       400,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	val, err := u.IncrChecked(ctx, "k")
+	require.NoError(t, err)
+	require.Equal(t, 2, val)
+
+	_, err = u.IncrChecked(ctx, "k")
+	require.ErrorIs(t, err, upstash.ErrNotInteger)
+
+	_, err = u.IncrByChecked(ctx, "k", 5)
+	require.ErrorIs(t, err, upstash.ErrNotInteger)
+
+	_, err = u.DecrByChecked(ctx, "k", 5)
+	require.ErrorIs(t, err, upstash.ErrNotInteger)
+}
+
+func TestUnitRestoreWithOptions(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"RESTORE", "k", float64(1767225600000), "payload", "REPLACE", "ABSTTL", "IDLETIME", float64(30)},
+			response:     "OK",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"RESTORE", "k", float64(0), "payload", "FREQ", float64(5)},
+			response:     "OK",
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+	idle := 30
+	freq := 5
+
+	res, err := u.RestoreWithOptions(ctx, "k", 1767225600000, []byte("payload"), upstash.RestoreOptions{
+		Replace:  true,
+		AbsTTL:   true,
+		IdleTime: &idle,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	res, err = u.RestoreWithOptions(ctx, "k", 0, []byte("payload"), upstash.RestoreOptions{Freq: &freq})
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	// DUMP payloads are RDB bytes that JSON cannot carry; they are rejected before any request.
+	_, err = u.RestoreWithOptions(ctx, "k", 0, []byte{0x00, 0x05, 0xff, 0xfe, 0x0b, 0x00}, upstash.RestoreOptions{})
+	require.ErrorContains(t, err, "valid UTF-8")
+}
+
+func TestUnitXRangeIter(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XRANGE", "s", "-", "+", "COUNT", float64(2)},
+			response: []any{
+				[]any{"1-0", []any{"f", "a"}},
+				[]any{"2-0", []any{"f", "b"}},
+			},
+			status: 200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"XRANGE", "s", "(2-0", "+", "COUNT", float64(2)},
+			response: []any{
+				[]any{"3-0", []any{"f", "c"}},
+			},
+			status: 200,
+		},
+	})
+	defer close()
+
+	it := u.XRangeIter(context.Background(), "s", "-", "+", 2)
+	ids := []string{}
+	values := []string{}
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+		values = append(values, it.Message().Values["f"])
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"1-0", "2-0", "3-0"}, ids)
+	require.Equal(t, []string{"a", "b", "c"}, values)
+}
+
+func TestUnitHello(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"HELLO", float64(3), "AUTH", "default", "secret"},
+			response: []any{
+				"server", "redis",
+				"version", "7.2.4",
+				"proto", float64(3),
+				"mode", "standalone",
+				"role", "master",
+				"modules", []any{},
+			},
+			status: 200,
+		},
+	})
+	defer close()
+
+	info, err := u.Hello(context.Background(), 3, "default", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "redis", info["server"])
+	require.Equal(t, "7.2.4", info["version"])
+	require.Equal(t, float64(3), info["proto"])
+	require.Equal(t, "master", info["role"])
+	require.Equal(t, []any{}, info["modules"])
+
+	// A lone password or extra values would otherwise send an unauthenticated HELLO.
+	_, err = u.Hello(context.Background(), 3, "secret")
+	require.ErrorContains(t, err, "auth must be a username and a password")
+	_, err = u.Hello(context.Background(), 3, "default", "secret", "extra")
+	require.ErrorContains(t, err, "got 3 values")
+}
+
+func TestUnitFunctionDumpRestore(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"FUNCTION", "DUMP"},
+			response:     "\u00f5\u00c3@X@]#!lua",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"FUNCTION", "RESTORE", "\u00f5\u00c3@X@]#!lua", "REPLACE"},
+			response:     "OK",
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	payload, err := u.FunctionDump(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\u00f5\u00c3@X@]#!lua"), payload)
+
+	res, err := u.FunctionRestore(ctx, payload, "REPLACE")
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	// A real dump holds RDB bytes and a CRC that are not valid UTF-8; it is rejected before any request.
+	_, err = u.FunctionRestore(ctx, []byte{0xf5, 0xc3, 0x40, 0x58, 0xff, 0x0b, 0x00, 0x9c}, "REPLACE")
+	require.ErrorContains(t, err, "valid UTF-8")
+}
+
+func TestUnitDocument(t *testing.T) {
+	type user struct {
+		Name string   `json:"name"`
+		Age  int      `json:"age"`
+		Tags []string `json:"tags"`
+	}
+
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"JSON.SET", "user:1", "$", `{"name":"Ada","age":36,"tags":["math"]}`},
+			response:     "OK",
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"JSON.GET", "user:1", "$"},
+			response:     `[{"name":"Ada","age":36,"tags":["math"]}]`,
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"JSON.GET", "user:2", "$"},
+			response:     nil,
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+	in := user{Name: "Ada", Age: 36, Tags: []string{"math"}}
+
+	err := u.PutDocument(ctx, "user:1", in)
+	require.NoError(t, err)
+
+	var out user
+	err = u.GetDocument(ctx, "user:1", &out)
+	require.NoError(t, err)
+	require.Equal(t, in, out)
+
+	err = u.GetDocument(ctx, "user:2", &out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+}
+
+func TestUnitJsonGetIntoSetStruct(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"JSON.SET", "u", "$.address", `{"city":"Paris"}`}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "u", "$.address"}, response: `[{"city":"Paris"}]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "u", ".address"}, response: `{"city":"Lyon"}`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "u", "$..city"}, response: `["Paris","Lyon"]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "u", "$.missing"}, response: `[]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "nokey", "$"}, response: nil, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "u", "$.address"}, response: `["not an object"]`, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.JsonSetStruct(ctx, "u", "$.address", address{City: "Paris"})
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	var a address
+	require.NoError(t, u.JsonGetInto(ctx, "u", "$.address", &a))
+	require.Equal(t, "Paris", a.City)
+
+	require.NoError(t, u.JsonGetInto(ctx, "u", ".address", &a))
+	require.Equal(t, "Lyon", a.City)
+
+	var cities []string
+	require.NoError(t, u.JsonGetInto(ctx, "u", "$..city", &cities))
+	require.Equal(t, []string{"Paris", "Lyon"}, cities)
+
+	require.ErrorIs(t, u.JsonGetInto(ctx, "u", "$.missing", &a), upstash.ErrNil)
+	require.ErrorIs(t, u.JsonGetInto(ctx, "nokey", "$", &a), upstash.ErrNil)
+	require.ErrorContains(t, u.JsonGetInto(ctx, "u", "$.address", &a), "unable to decode json into")
+
+	_, err = u.JsonSetStruct(ctx, "u", "$", make(chan int))
+	require.ErrorContains(t, err, "unable to marshal")
+}
+
+func TestUnitHScanAll(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"HSCAN", "h", "0", "COUNT", float64(2)},
+			response:     []any{"7", []any{"f1", "v1", "f2", "v2"}},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"HSCAN", "h", "7", "COUNT", float64(2)},
+			response:     []any{"0", []any{"f3", "v3"}},
+			status:       200,
+		},
+	})
+	defer close()
+
+	all, err := u.HScanAll(context.Background(), "h", upstash.ScanOptions{Count: 2})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"f1": "v1", "f2": "v2", "f3": "v3"}, all)
+}
+
+func TestUnitSMembersSet(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"SMEMBERS", "s"},
+			response:     []any{"m1", "m2"},
+			status:       200,
+		},
+	})
+	defer close()
+
+	members, err := u.SMembersSet(context.Background(), "s")
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"m1": {}, "m2": {}}, members)
+}
+
+func TestUnitZAddScoreValidation(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"ZADD", "zs", "+inf", "top"},
+			response:     float64(1),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"ZINCRBY", "zs", "-inf", "top"},
+			response:     "-inf",
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.ZAdd(ctx, "zs", math.Inf(1), "top")
+	require.NoError(t, err)
+	require.Equal(t, 1, res)
+
+	score, err := u.ZIncrBy(ctx, "zs", math.Inf(-1), "top")
+	require.NoError(t, err)
+	require.True(t, math.IsInf(score, -1))
+
+	_, err = u.ZAdd(ctx, "zs", math.NaN(), "bad")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "NaN")
+
+	_, err = u.ZIncrBy(ctx, "zs", math.NaN(), "bad")
+	require.Error(t, err)
+}
+
+func TestUnitBlockingRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": []any{"l", "v"}})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{
+		Url:                    server.URL,
+		Token:                  "t",
+		RequestTimeout:         20 * time.Millisecond,
+		BlockingRequestTimeout: 5 * time.Second,
+	})
+	ctx := context.Background()
+
+	res, err := u.BLPop(ctx, 1, "l")
+	require.NoError(t, err)
+	require.Equal(t, []string{"l", "v"}, res)
+
+	_, err = u.LRange(ctx, "l", 0, -1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestUnitXInfoStreamFull(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XINFO", "STREAM", "s", "FULL", "COUNT", float64(10)},
+			response: []any{
+				"length", float64(2),
+				"radix-tree-keys", float64(1),
+				"radix-tree-nodes", float64(2),
+				"last-generated-id", "2-0",
+				"max-deleted-entry-id", "0-0",
+				"entries-added", float64(2),
+				"recorded-first-entry-id", "1-0",
+				"entries", []any{
+					[]any{"1-0", []any{"f", "a"}},
+					[]any{"2-0", []any{"f", "b"}},
+				},
+				"groups", []any{
+					[]any{
+						"name", "g1",
+						"last-delivered-id", "2-0",
+						"entries-read", float64(2),
+						"lag", float64(0),
+						"pel-count", float64(1),
+						"pel", []any{
+							[]any{"2-0", "c1", float64(1700000000000), float64(3)},
+						},
+						"consumers", []any{
+							[]any{
+								"name", "c1",
+								"seen-time", float64(1700000000100),
+								"active-time", float64(1700000000050),
+								"pel-count", float64(1),
+								"pel", []any{
+									[]any{"2-0", float64(1700000000000), float64(3)},
+								},
+							},
+						},
+					},
+				},
+			},
+			status: 200,
+		},
+	})
+	defer close()
+
+	info, err := u.XInfoStreamFull(context.Background(), "s", 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), info.Length)
+	require.Equal(t, "2-0", info.LastGeneratedID)
+	require.Equal(t, "1-0", info.RecordedFirstEntryID)
+	require.Len(t, info.Entries, 2)
+	require.Equal(t, "b", info.Entries[1].Values["f"])
+	require.Equal(t, float64(1), info.Raw["radix-tree-keys"])
+
+	require.Len(t, info.Groups, 1)
+	group := info.Groups[0]
+	require.Equal(t, "g1", group.Name)
+	require.Equal(t, int64(2), group.EntriesRead)
+	require.Equal(t, []upstash.XPendingEntry{
+		{ID: "2-0", Consumer: "c1", DeliveryTime: 1700000000000, DeliveryCount: 3},
+	}, group.Pending)
+
+	require.Len(t, group.Consumers, 1)
+	consumer := group.Consumers[0]
+	require.Equal(t, "c1", consumer.Name)
+	require.Equal(t, int64(1700000000050), consumer.ActiveTime)
+	require.Equal(t, group.Pending, consumer.Pending)
+}
+
+func TestUnitPubSubIntrospection(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"PUBSUB", "CHANNELS", "news.*"},
+			response:     []any{"news.tech", "news.sport"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"PUBSUB", "CHANNELS"},
+			response:     []any{},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"PUBSUB", "NUMSUB", "a", "b"},
+			response:     []any{"a", float64(2), "b", float64(0)},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"PUBSUB", "NUMPAT"},
+			response:     float64(3),
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	channels, err := u.PubSubChannels(ctx, "news.*")
+	require.NoError(t, err)
+	require.Equal(t, []string{"news.tech", "news.sport"}, channels)
+
+	channels, err = u.PubSubChannels(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, channels)
+
+	numSub, err := u.PubSubNumSub(ctx, "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 2, "b": 0}, numSub)
+
+	numPat, err := u.PubSubNumPat(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, numPat)
+}
+
+func TestUnitGetPrefixSuffix(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:   "GET",
+			path:     "/getrange/k/0/2",
+			response: "hel",
+			status:   200,
+		},
+		{
+			method:   "GET",
+			path:     "/getrange/k/-3/-1",
+			response: "llo",
+			status:   200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	prefix, err := u.GetPrefix(ctx, "k", 3)
+	require.NoError(t, err)
+	require.Equal(t, "hel", prefix)
+
+	suffix, err := u.GetSuffix(ctx, "k", 3)
+	require.NoError(t, err)
+	require.Equal(t, "llo", suffix)
+
+	empty, err := u.GetSuffix(ctx, "k", 0)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+}
+
+func TestUnitZRangeUnified(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"ZRANGE", "zs", "+inf", "(1", "BYSCORE", "REV", "WITHSCORES"},
+			response:     []any{"m3", "3", "m2", "2"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"ZRANGE", "zs", "[a", "+", "BYLEX", "LIMIT", float64(1), float64(2)},
+			response:     []any{"b", "c"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"ZRANGE", "zs", float64(0), float64(-1)},
+			response:     []any{},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.ZRangeUnified(ctx, "zs", "+inf", "(1", upstash.ZRangeOptions{ByScore: true, Rev: true, WithScores: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"m3", "3", "m2", "2"}, res)
+
+	res, err = u.ZRangeUnified(ctx, "zs", "[a", "+", upstash.ZRangeOptions{ByLex: true, Limit: &upstash.ZRangeLimit{Offset: 1, Count: 2}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, res)
+
+	res, err = u.ZRangeUnified(ctx, "zs", 0, -1, upstash.ZRangeOptions{})
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
+
+func TestUnitUseNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"result":9007199254740993}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", UseNumber: true})
+	val, err := u.Incr(ctx, "counter")
+	require.NoError(t, err)
+	require.Equal(t, 9007199254740993, val)
+
+	res, err := u.Send(ctx, "INCR", "counter")
+	require.NoError(t, err)
+	require.Equal(t, json.Number("9007199254740993"), res)
+
+	// Without UseNumber the value is rounded to the nearest float64.
+	u, _ = upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	val, err = u.Incr(ctx, "counter")
+	require.NoError(t, err)
+	require.Equal(t, 9007199254740992, val)
+}
+
+func TestUnitBoolVariants(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"SISMEMBER", "s", "m"},
+			response:     float64(1),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"HEXISTS", "h", "f"},
+			response:     float64(0),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"setnx", "k", "v"},
+			response:     float64(0),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"EXPIRE", "k", float64(10)},
+			response:     float64(1),
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	ok, err := u.SIsMemberBool(ctx, "s", "m")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = u.HExistsBool(ctx, "h", "f")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = u.SetNXBool(ctx, "k", "v")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = u.ExpireBool(ctx, "k", 10)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestUnitHashBytesBase64(t *testing.T) {
+	field := []byte("\x00\x01f\xc3\xa9")
+	value := []byte("v\x00")
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "base64", r.Header.Get("Upstash-Encoding"))
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+		switch step {
+		case 0:
+			require.Equal(t, []any{"HSET", "h", string(field), string(value)}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": float64(1)})
+		case 1:
+			require.Equal(t, []any{"HGET", "h", string(field)}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": base64.StdEncoding.EncodeToString(value)})
+		case 2:
+			require.Equal(t, []any{"HKEYS", "h"}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": []any{base64.StdEncoding.EncodeToString(field)}})
+		}
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableBase64: true})
+	ctx := context.Background()
+
+	added, err := u.HSetBytes(ctx, "h", field, value)
+	require.NoError(t, err)
+	require.Equal(t, 1, added)
+
+	got, err := u.HGetBytes(ctx, "h", field)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	keys, err := u.HKeys(ctx, "h")
+	require.NoError(t, err)
+	require.Equal(t, []string{string(field)}, keys)
+
+	_, err = u.HSetBytes(ctx, "h", []byte{0xff, 0xfe}, value)
+	require.Error(t, err)
+	require.Equal(t, 3, step)
+}
+
+func TestUnitActiveSubscriptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		_, _ = fmt.Fprint(w, "data: subscribe,ch,1\n\n")
+		_, _ = fmt.Fprint(w, "data: ready\n\n")
+		flusher.Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	a, err := u.Subscribe(ctx, "a")
+	require.NoError(t, err)
+	b, err := u.Subscribe(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, "ready", <-a)
+	require.Equal(t, "ready", <-b)
+
+	require.Equal(t, []string{"a", "b"}, u.ActiveSubscriptions())
+
+	u.CloseAllSubscriptions()
+	for range a {
+	}
+	for range b {
+	}
+	require.Eventually(t, func() bool {
+		return len(u.ActiveSubscriptions()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestUnitGeoSearchStoreTyped(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"GEOSEARCHSTORE", "dst", "src", "FROMLONLAT", float64(15), float64(37), "BYRADIUS", float64(200), "km", "ASC", "COUNT", float64(3), "STOREDIST"},
+			response:     float64(2),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"GEOSEARCHSTORE", "dst", "src", "FROMMEMBER", "Palermo", "BYBOX", float64(400), float64(400), "m"},
+			response:     float64(1),
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	stored, err := u.GeoSearchStoreTyped(ctx, "dst", "src", upstash.GeoSearchOptions{
+		FromLonLat: &[2]float64{15, 37},
+		ByRadius:   200,
+		Unit:       "km",
+		Sort:       "ASC",
+		Count:      3,
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, stored)
+
+	stored, err = u.GeoSearchStoreTyped(ctx, "dst", "src", upstash.GeoSearchOptions{
+		FromMember: "Palermo",
+		ByBox:      &[2]float64{400, 400},
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, stored)
+}
+
+func TestUnitHRandField(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"HRANDFIELD", "h", float64(2)},
+			response:     []any{"f1", "f2"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"HRANDFIELD", "h", float64(-3), "WITHVALUES"},
+			response:     []any{"f1", "v1", "f2", "v2", "f1", "v1"},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	fields, err := u.HRandField(ctx, "h", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"f1", "f2"}, fields)
+
+	pairs, err := u.HRandFieldWithValues(ctx, "h", -3)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.KV{
+		{Key: "f1", Value: "v1"},
+		{Key: "f2", Value: "v2"},
+		{Key: "f1", Value: "v1"},
+	}, pairs)
+}
+
+func TestUnitSMembersAuto(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"SCARD", "small"},
+			response:     float64(2),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"SMEMBERS", "small"},
+			response:     []any{"m1", "m2"},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"SCARD", "large"},
+			response:     float64(3),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"SSCAN", "large", "0", "COUNT", float64(2)},
+			response:     []any{"5", []any{"m1", "m2"}},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"SSCAN", "large", "5", "COUNT", float64(2)},
+			response:     []any{"0", []any{"m2", "m3"}},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	members, err := u.SMembersAuto(ctx, "small", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m1", "m2"}, members)
+
+	members, err = u.SMembersAuto(ctx, "large", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m1", "m2", "m3"}, members)
+}
+
+func TestUnitSetWithJitterTTL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		require.Len(t, body, 5)
+		require.Equal(t, []any{"set", "k", "v", "px"}, body[:4])
+		ms, err := strconv.Atoi(body[4].(string))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, ms, 60000)
+		require.LessOrEqual(t, ms, 70000)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "OK"})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	for i := 0; i < 20; i++ {
+		err := u.SetWithJitterTTL(context.Background(), "k", "v", time.Minute, 10*time.Second)
+		require.NoError(t, err)
+	}
+
+	// Invalid TTLs are rejected before any request, rather than storing the key without expiry.
+	require.ErrorContains(t, u.SetWithJitterTTL(context.Background(), "k", "v", 0, 0), "invalid ttl")
+	require.ErrorContains(t, u.SetWithJitterTTL(context.Background(), "k", "v", 500*time.Microsecond, 0), "invalid ttl")
+	require.ErrorContains(t, u.SetWithJitterTTL(context.Background(), "k", "v", -time.Second, time.Second), "invalid ttl")
+	require.ErrorContains(t, u.SetWithJitterTTL(context.Background(), "k", "v", time.Minute, -time.Second), "invalid jitter")
+}
+
+func TestUnitHSetMulti(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"HSET", "h", "f1", "v1", "f2", "v2"},
+			// f1 already existed, so only f2 is counted.
+			response: float64(1),
+			status:   200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"HSET", "h", "f3", "v3"},
+			response:     float64(1),
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"HSET", "h", "f3", "v4"},
+			response:     float64(0),
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	created, err := u.HSetMulti(ctx, "h", []upstash.KV{{Key: "f1", Value: "v1"}, {Key: "f2", Value: "v2"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, created)
+
+	isNew, err := u.HSetBool(ctx, "h", "f3", "v3")
+	require.NoError(t, err)
+	require.True(t, isNew)
+
+	isNew, err = u.HSetBool(ctx, "h", "f3", "v4")
+	require.NoError(t, err)
+	require.False(t, isNew)
+}
+
+func TestUnitRequestLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "OK"})
+	}))
+	defer server.Close()
+
+	var loggedMethod, loggedUrl string
+	var loggedBody []byte
+	u, _ := upstash.New(upstash.Options{
+		Url:   server.URL,
+		Token: "secret-token",
+		RequestLogger: func(method, url string, body []byte) {
+			loggedMethod = method
+			loggedUrl = url
+			loggedBody = body
+		},
+	})
+
+	err := u.Set(context.Background(), "k", "v")
+	require.NoError(t, err)
+	require.Equal(t, "POST", loggedMethod)
+	require.Equal(t, server.URL+"/", loggedUrl)
+	require.JSONEq(t, `["set","k","v"]`, string(loggedBody))
+	require.NotContains(t, loggedUrl+string(loggedBody), "secret-token")
+}
+
+func TestUnitExpireStrict(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"EXPIRE", "k", float64(10)},
+				[]any{"EXISTS", "k"},
+			},
+			response: []any{
+				map[string]any{"result": float64(1)},
+				map[string]any{"result": float64(1)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"EXPIRE", "missing", float64(10)},
+				[]any{"EXISTS", "missing"},
+			},
+			response: []any{
+				map[string]any{"result": float64(0)},
+				map[string]any{"result": float64(0)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	err := u.ExpireStrict(ctx, "k", 10)
+	require.NoError(t, err)
+
+	err = u.ExpireStrict(ctx, "missing", 10)
+	require.ErrorIs(t, err, upstash.ErrKeyNotFound)
+}
+
+func TestUnitPushConfirmed(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"RPUSH", "queue", "a", "b"},
+				[]any{"LLEN", "queue"},
+			},
+			response: []any{
+				map[string]any{"result": float64(2)},
+				map[string]any{"result": float64(2)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	n, err := u.PushConfirmed(context.Background(), "queue", "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestUnitZAddAndCheckEncoding(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"ZADD", "z", float64(1), "a", float64(2.5), "b"},
+			response:     float64(2),
+			status:       200,
+		},
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"ZADD", "z", float64(3), "c"},
+				[]any{"OBJECT", "ENCODING", "z"},
+			},
+			response: []any{
+				map[string]any{"result": float64(1)},
+				map[string]any{"result": "skiplist"},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"OBJECT", "ENCODING", "z"},
+			response:     "skiplist",
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	n, err := u.ZAddMulti(ctx, "z", []upstash.ZMember{{Score: 1, Member: "a"}, {Score: 2.5, Member: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	n, enc, err := u.ZAddAndCheckEncoding(ctx, "z", []upstash.ZMember{{Score: 3, Member: "c"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, "skiplist", enc)
+
+	enc, err = u.ObjectEncoding(ctx, "z")
+	require.NoError(t, err)
+	require.Equal(t, "skiplist", enc)
+}
+
+func TestUnitListEncoding(t *testing.T) {
+	encodings := []struct {
+		encoding  string
+		quicklist bool
+	}{
+		{"listpack", false},
+		{"ziplist", false},
+		{"quicklist", true},
+		{"linkedlist", true},
+		{"", false},
+	}
+	handlers := []mockHandler{}
+	for _, e := range encodings {
+		var response any = e.encoding
+		if e.encoding == "" {
+			response = nil
+		}
+		handlers = append(handlers,
+			mockHandler{method: "POST", expectedBody: []any{"OBJECT", "ENCODING", "l"}, response: response, status: 200},
+			mockHandler{method: "POST", expectedBody: []any{"OBJECT", "ENCODING", "l"}, response: response, status: 200},
+		)
+	}
+	u, close := setupMockServer(t, handlers)
+	defer close()
+
+	ctx := context.Background()
+	for _, e := range encodings {
+		enc, err := u.ListEncoding(ctx, "l")
+		require.NoError(t, err)
+		require.Equal(t, e.encoding, enc)
+
+		quicklist, err := u.ListIsQuicklist(ctx, "l")
+		require.NoError(t, err)
+		require.Equal(t, e.quicklist, quicklist, e.encoding)
+	}
+}
+
+func TestUnitSubscribeConfirmation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+
+		if r.URL.Path == "/subscribe/closed" {
+			return
+		}
+		_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		_, _ = fmt.Fprint(w, "data: subscribe,news,1\n\n")
+		_, _ = fmt.Fprint(w, "data: message,news,first\n\n")
+		flusher.Flush()
+		_, _ = fmt.Fprint(w, "data: message,news,second\n\n")
+		flusher.Flush()
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs, err := u.Subscribe(ctx, "news")
+	require.NoError(t, err)
+	require.Equal(t, "message,news,first", <-msgs)
+	require.Equal(t, "message,news,second", <-msgs)
+
+	_, err = u.Subscribe(ctx, "closed")
+	require.ErrorContains(t, err, "before confirmation")
+	require.Empty(t, u.ActiveSubscriptions())
+}
+
+func TestUnitMultiPushValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	tx := u.Multi()
+	tx.Push("SET", "k", "v")
+	tx.Push("")
+	tx.Push("GET", nil)
+	_, err := tx.Exec(ctx)
+	require.EqualError(t, err, "command 1: empty command name")
+
+	tx.Discard()
+	tx.Push("GET", nil)
+	_, err = tx.Exec(ctx)
+	require.EqualError(t, err, "command 0 (GET): nil argument at position 0")
+}
+
+func TestUnitStreamValuesOrdered(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XRANGE", "s", "-", "+"},
+			response: []any{
+				[]any{"1-0", []any{"tag", "a", "user", "u1", "tag", "b"}},
+			},
+			status: 200,
+		},
+	})
+	defer close()
+
+	msgs, err := u.XRange(context.Background(), "s", "-", "+")
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, map[string]string{"tag": "b", "user": "u1"}, msgs[0].Values)
+	require.Equal(t, []upstash.KV{
+		{Key: "tag", Value: "a"},
+		{Key: "user", Value: "u1"},
+		{Key: "tag", Value: "b"},
+	}, msgs[0].ValuesOrdered)
+}
+
+func TestUnitConsumeList(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"BLPOP", "q", float64(1)}, response: []any{"q", "a"}, status: 200},
+		{method: "POST", expectedBody: []any{"BLPOP", "q", float64(1)}, response: nil, status: 200},
+		{method: "POST", expectedBody: []any{"BLPOP", "q", float64(1)}, response: []any{"q", "b"}, status: 200},
+		{method: "POST", expectedBody: []any{"BLMOVE", "q", "q:processing", "LEFT", "RIGHT", float64(2)}, response: "x", status: 200},
+		{method: "POST", expectedBody: []any{"LREM", "q:processing", float64(1), "x"}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"BLMOVE", "q", "q:processing", "LEFT", "RIGHT", float64(2)}, response: "y", status: 200},
+	})
+	defer close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	err := u.ConsumeList(ctx, "q", func(value string) error {
+		got = append(got, value)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	}, 500*time.Millisecond)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []string{"a", "b"}, got)
+
+	errFailed := errors.New("failed")
+	got = nil
+	err = u.ConsumeListReliable(context.Background(), "q", "q:processing", func(value string) error {
+		got = append(got, value)
+		if value == "y" {
+			return errFailed
+		}
+		return nil
+	}, 2*time.Second)
+	require.ErrorIs(t, err, errFailed)
+	require.Equal(t, []string{"x", "y"}, got)
+}
+
+func TestUnitMaxCollectionResult(t *testing.T) {
+	var commands []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		command := fmt.Sprint(body[0])
+		commands = append(commands, command)
+		switch command {
+		case "LLEN", "ZCARD", "ZCOUNT", "ZLEXCOUNT":
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": 4})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": []any{"a", "b", "c", "d"}})
+		}
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", MaxCollectionResult: 3})
+	ctx := context.Background()
+
+	_, err := u.SPop(ctx, "s", 4)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.SRandMember(ctx, "s", -4)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.LRange(ctx, "l", 0, 3)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRange(ctx, "z", -4, -1)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeByScore(ctx, "z", "-inf", "+inf", 4)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeUnified(ctx, "z", "-inf", "+inf", upstash.ZRangeOptions{ByScore: true, Limit: &upstash.ZRangeLimit{Count: 10}})
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	require.Empty(t, commands)
+
+	// Open-ended ranges are measured first, so the range itself is never downloaded.
+	_, err = u.LRange(ctx, "l", 0, -1)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeWithScores(ctx, "z", 0, -1)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeByScore(ctx, "z", "-inf", "+inf")
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeByLex(ctx, "z", "-", "+")
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeUnified(ctx, "z", "+", "-", upstash.ZRangeOptions{ByLex: true, Rev: true})
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	_, err = u.ZRangeUnified(ctx, "z", 0, -1, upstash.ZRangeOptions{})
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	require.Equal(t, []string{"LLEN", "ZCARD", "ZCOUNT", "ZLEXCOUNT", "ZLEXCOUNT", "ZCARD"}, commands)
+
+	// A range that fits the collection measured up front is sent, and its reply is still checked.
+	commands = nil
+	_, err = u.LRange(ctx, "l", -2, 10)
+	require.ErrorIs(t, err, upstash.ErrCollectionTooLarge)
+	require.Equal(t, []string{"LLEN", "LRANGE"}, commands)
+
+	_, err = u.SPop(ctx, "s", 3)
+	require.NoError(t, err)
+}
+
+func TestUnitEnsureDurable(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"WAIT", float64(1), float64(1500)}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"WAIT", float64(1), float64(1)}, response: float64(0), status: 200},
+		{method: "POST", expectedBody: []any{"WAITAOF", float64(1), float64(0), float64(100)}, response: []any{float64(1), float64(0)}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	err := u.EnsureDurable(ctx, 1500*time.Millisecond)
+	require.NoError(t, err)
+
+	err = u.EnsureDurable(ctx, 0)
+	require.ErrorContains(t, err, "not acknowledged")
+
+	local, replicas, err := u.WaitAOF(ctx, 1, 0, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, local)
+	require.Equal(t, 0, replicas)
+}
+
+func TestUnitJsonGetSingle(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"JSON.GET", "doc", "$.name"}, response: `["alice"]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "doc", "$.missing"}, response: `[]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "doc", "$..id"}, response: `[1,2]`, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.GET", "nokey", "$.name"}, response: nil, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	v, err := u.JsonGetSingle(ctx, "doc", "$.name")
+	require.NoError(t, err)
+	require.Equal(t, "alice", v)
+
+	_, err = u.JsonGetSingle(ctx, "doc", "$.missing")
+	require.ErrorIs(t, err, upstash.ErrNil)
+
+	v, err = u.JsonGetSingle(ctx, "doc", "$..id")
+	require.NoError(t, err)
+	require.Equal(t, []any{float64(1), float64(2)}, v)
+
+	_, err = u.JsonGetSingle(ctx, "nokey", "$.name")
+	require.ErrorIs(t, err, upstash.ErrNil)
+}
+
+func TestUnitUniqueCountWindow(t *testing.T) {
+	var body [][]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/pipeline", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_ = json.NewEncoder(w).Encode([]any{
+			map[string]any{"result": 1},
+			map[string]any{"result": 1},
+			map[string]any{"result": 2},
+		})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	window := time.Hour
+
+	before := time.Now().UnixMilli() / window.Milliseconds()
+	n, err := u.UniqueCountWindow(context.Background(), "visitors", []string{"a", "b"}, window, 24)
+	after := time.Now().UnixMilli() / window.Milliseconds()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.Len(t, body, 3)
+	key := body[0][1].(string)
+	bucket, err := strconv.ParseInt(key[len("visitors:"):], 10, 64)
+	require.NoError(t, err)
+	require.True(t, bucket == before || bucket == after)
+	require.Equal(t, []any{"PFADD", key, "a", "b"}, body[0])
+	// The bucket outlives its own window so that the last 24 windows can be merged.
+	require.Equal(t, []any{"PEXPIREAT", key, float64((bucket + 24) * window.Milliseconds())}, body[1])
+	require.Equal(t, []any{"PFCOUNT", key}, body[2])
+
+	_, err = u.UniqueCountWindow(context.Background(), "visitors", []string{"a"}, 0, 1)
+	require.Error(t, err)
+
+	_, err = u.UniqueCountWindow(context.Background(), "visitors", []string{"a"}, window, 0)
+	require.ErrorContains(t, err, "invalid retention")
+}
+
+func TestUnitUniqueCountMerged(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"PFCOUNT", "visitors:1", "visitors:2", "visitors:3"}, response: float64(42), status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	n, err := u.UniqueCountMerged(ctx, []string{"visitors:1", "visitors:2", "visitors:3"})
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+
+	_, err = u.UniqueCountMerged(ctx, nil)
+	require.Error(t, err)
+}
+
+func TestUnitSetWithTTL(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"set", "k", "v", "px", "1500"}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"set", "k", "v", "ex", "60"}, response: "OK", status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	require.NoError(t, u.SetWithTTL(ctx, "k", "v", 1500*time.Millisecond))
+	require.NoError(t, u.SetWithTTL(ctx, "k", "v", time.Minute))
+	require.Error(t, u.SetWithTTL(ctx, "k", "v", 0))
+}
+
+func TestUnitErrWrongType(t *testing.T) {
+	wrongType := "WRONGTYPE Operation against a key holding the wrong kind of value"
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "GET", path: "/get/l", response: map[string]any{"error": wrongType}, rawResponse: true, status: 400},
+		{method: "GET", path: "/get/l", response: map[string]any{"error": wrongType}, rawResponse: true, status: 200},
+		{method: "GET", path: "/get/k", response: map[string]any{"error": "ERR syntax error"}, rawResponse: true, status: 400},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	_, err := u.Get(ctx, "l")
+	require.True(t, errors.Is(err, upstash.ErrWrongType))
+	require.ErrorContains(t, err, "status code 400")
+
+	_, err = u.Get(ctx, "l")
+	require.True(t, errors.Is(err, upstash.ErrWrongType))
+	require.EqualError(t, err, wrongType)
+
+	_, err = u.Get(ctx, "k")
+	require.Error(t, err)
+	require.False(t, errors.Is(err, upstash.ErrWrongType))
+}
+
+func TestUnitXAddCapped(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XADD", "events", "MAXLEN", "~", float64(1000), "*", "type", "login"},
+			response:     "1700000000000-0",
+			status:       200,
+		},
+	})
+	defer close()
+
+	id, err := u.XAddCapped(context.Background(), "events", map[string]string{"type": "login"}, 1000)
+	require.NoError(t, err)
+	require.Equal(t, "1700000000000-0", id)
+}
+
+func TestUnitScriptAndFunctionKill(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"SCRIPT", "KILL"}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"SCRIPT", "KILL"}, response: map[string]any{"error": "NOTBUSY No scripts in execution right now."}, rawResponse: true, status: 400},
+		{method: "POST", expectedBody: []any{"FUNCTION", "KILL"}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"FUNCTION", "KILL"}, response: map[string]any{"error": "NOTBUSY No scripts in execution right now."}, rawResponse: true, status: 400},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.ScriptKill(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	_, err = u.ScriptKill(ctx)
+	require.ErrorIs(t, err, upstash.ErrNotBusy)
+
+	res, err = u.FunctionKill(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+
+	_, err = u.FunctionKill(ctx)
+	require.ErrorIs(t, err, upstash.ErrNotBusy)
+}
+
+func TestUnitClientList(t *testing.T) {
+	list := "id=3 addr=127.0.0.1:50188 laddr=127.0.0.1:6379 name= age=12 db=0 cmd=client|list\n" +
+		"id=5 addr=127.0.0.1:50190 laddr=127.0.0.1:6379 name=worker age=3 db=0 cmd=blpop\n"
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"CLIENT", "LIST"}, response: list, status: 200},
+		{method: "POST", expectedBody: []any{"CLIENT", "KILL", "ID", float64(5), "TYPE", "normal"}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"CLIENT", "LIST"}, response: map[string]any{"error": "NOPERM this user has no permissions to run the 'client|list' command"}, rawResponse: true, status: 400},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	clients, err := u.ClientListParsed(ctx)
+	require.NoError(t, err)
+	require.Len(t, clients, 2)
+	require.Equal(t, "3", clients[0]["id"])
+	require.Equal(t, "", clients[0]["name"])
+	require.Equal(t, "client|list", clients[0]["cmd"])
+	require.Equal(t, "worker", clients[1]["name"])
+	require.Equal(t, "127.0.0.1:50190", clients[1]["addr"])
+
+	n, err := u.ClientKill(ctx, upstash.ClientKillOptions{ID: 5, Type: "normal"})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = u.ClientKill(ctx, upstash.ClientKillOptions{})
+	require.Error(t, err)
+
+	_, err = u.ClientList(ctx)
+	require.ErrorIs(t, err, upstash.ErrNoPerm)
+}
+
+func TestUnitPersistAndGetOldTTL(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"TTL", "k"},
+				[]any{"PERSIST", "k"},
+			},
+			response: []any{
+				map[string]any{"result": float64(120)},
+				map[string]any{"result": float64(1)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"TTL", "forever"},
+				[]any{"PERSIST", "forever"},
+			},
+			response: []any{
+				map[string]any{"result": float64(-1)},
+				map[string]any{"result": float64(0)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	removed, ttl, err := u.PersistAndGetOldTTL(ctx, "k")
+	require.NoError(t, err)
+	require.True(t, removed)
+	require.Equal(t, 120, ttl)
+
+	removed, ttl, err = u.PersistAndGetOldTTL(ctx, "forever")
+	require.NoError(t, err)
+	require.False(t, removed)
+	require.Equal(t, -1, ttl)
+}
+
+func TestUnitPipelineExecWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	ctx := context.Background()
+
+	pipe := u.Pipeline()
+	pipe.Push("BLPOP", "q", 10)
+	start := time.Now()
+	_, err := pipe.ExecWithTimeout(ctx, 50*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	pipe = u.Pipeline()
+	pipe.Push("BLPOP", "q", 10)
+	pipe.Push("GET", "k")
+	_, err = pipe.ExecWithTimeout(ctx, time.Second)
+	require.ErrorContains(t, err, "mixes blocking and non-blocking")
+}
+
+func TestUnitScanAutoTune(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"SCAN", "0", "MATCH", "user:*", "COUNT", float64(10)}, response: []any{"7", []any{"user:1"}}, status: 200},
+		{method: "POST", expectedBody: []any{"SCAN", "7", "MATCH", "user:*", "COUNT", float64(20)}, response: []any{"12", []any{}}, status: 200},
+		{method: "POST", expectedBody: []any{"SCAN", "12", "MATCH", "user:*", "COUNT", float64(40)}, response: []any{"0", []any{"user:2", "user:3"}}, status: 200},
+	})
+	defer close()
+
+	it := u.ScanAutoTune(context.Background(), upstash.ScanOptions{Match: "user:*"})
+	keys := []string{}
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"user:1", "user:2", "user:3"}, keys)
+}
+
+func TestUnitScanIterators(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"SCAN", "0", "COUNT", float64(10)}, response: []any{"5", []any{"a"}}, status: 200},
+		{method: "POST", expectedBody: []any{"SCAN", "5", "COUNT", float64(10)}, response: []any{"0", []any{"b"}}, status: 200},
+		{method: "POST", expectedBody: []any{"HSCAN", "h", "0"}, response: []any{"3", []any{"f1", "v1"}}, status: 200},
+		{method: "POST", expectedBody: []any{"HSCAN", "h", "3"}, response: []any{"0", []any{"f2", "v2"}}, status: 200},
+		{method: "POST", expectedBody: []any{"SSCAN", "s", "0", "MATCH", "m*"}, response: []any{"0", []any{"m1", "m2"}}, status: 200},
+		{method: "POST", expectedBody: []any{"ZSCAN", "z", "0"}, response: []any{"0", []any{"one", "1"}}, status: 200},
+		{method: "POST", expectedBody: []any{"SSCAN", "bad", "0"}, response: map[string]any{"error": "WRONGTYPE"}, rawResponse: true, status: 400},
+	})
+	defer close()
+
+	ctx := context.Background()
+	collect := func(it *upstash.ScanIterator) []string {
+		vals := []string{}
+		for it.Next() {
+			vals = append(vals, it.Val())
+		}
+		require.NoError(t, it.Err())
+		return vals
+	}
+
+	require.Equal(t, []string{"a", "b"}, collect(u.ScanIterator(ctx, upstash.ScanOptions{Count: 10})))
+	require.Equal(t, []string{"f1", "v1", "f2", "v2"}, collect(u.HScanIterator(ctx, "h", upstash.ScanOptions{})))
+	require.Equal(t, []string{"m1", "m2"}, collect(u.SScanIterator(ctx, "s", upstash.ScanOptions{Match: "m*"})))
+	require.Equal(t, []string{"one", "1"}, collect(u.ZScanIterator(ctx, "z", upstash.ScanOptions{})))
+
+	it := u.SScanIterator(ctx, "bad", upstash.ScanOptions{})
+	require.False(t, it.Next())
+	require.Error(t, it.Err())
+}
+
+func TestUnitSortGrouped(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"SORT", "ids", "BY", "weight_*", "LIMIT", float64(0), float64(10), "GET", "#", "GET", "name_*", "DESC"},
+			response:     []any{"2", "bob", "1", nil},
+			status:       200,
+		},
+		{method: "POST", expectedBody: []any{"SORT", "ids", "ALPHA"}, response: []any{"a", "b"}, status: 200},
+		{method: "POST", expectedBody: []any{"SORT", "ids", "GET", "#", "GET", "name_*"}, response: []any{"1", "ann", "2"}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	groups, err := u.SortGrouped(ctx, "ids", upstash.SortOptions{
+		By:    "weight_*",
+		Limit: &upstash.SortLimit{Offset: 0, Count: 10},
+		Get:   []string{"#", "name_*"},
+		Desc:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"2", "bob"}, {"1", ""}}, groups)
+
+	groups, err = u.SortGrouped(ctx, "ids", upstash.SortOptions{Alpha: true})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a"}, {"b"}}, groups)
+
+	_, err = u.SortGrouped(ctx, "ids", upstash.SortOptions{Get: []string{"#", "name_*"}})
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+}
+
+func TestUnitBitFieldInts(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"BITFIELD", "bf", "GET", "i8", float64(0), "GET", "u8", float64(0), "INCRBY", "u2", float64(0), float64(5)},
+			response:     []any{float64(-1), float64(255), nil},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"BITFIELD", "bf", "GET", "i64", float64(0)},
+			response:     []any{float64(1 << 63)},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.BitFieldInts(ctx, "bf", "GET", "i8", 0, "GET", "u8", 0, "INCRBY", "u2", 0, 5)
+	require.NoError(t, err)
+	require.Equal(t, []int64{-1, 255, 0}, res)
+
+	_, err = u.BitFieldInts(ctx, "bf", "GET", "i64", 0)
+	require.ErrorContains(t, err, "out of int64 range")
+}
+
+func TestUnitBitFieldIntsUseNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"result":[9223372036854775807,-9223372036854775808]}`)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", UseNumber: true})
+
+	res, err := u.BitFieldInts(context.Background(), "bf", "GET", "u63", 0, "GET", "i64", 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{math.MaxInt64, math.MinInt64}, res)
+}
+
+func TestUnitBatchSizes(t *testing.T) {
+	var bodies [][][]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/pipeline", r.URL.Path)
+		var body [][]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		results := make([]any, len(body))
+		for i := range body {
+			results[i] = map[string]any{"result": 2}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{
+		Url:          server.URL,
+		Token:        "t",
+		MaxBatchSize: 3,
+		BatchSizes:   map[string]int{"zadd": 2},
+	})
+	ctx := context.Background()
+
+	n, err := u.SAdd(ctx, "s", "a", "b", "c", "d")
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	n, err = u.ZAddMulti(ctx, "z", []upstash.ZMember{{Score: 1, Member: "a"}, {Score: 2, Member: "b"}, {Score: 3, Member: "c"}})
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	require.Equal(t, [][][]any{
+		{
+			[]any{"SADD", "s", "a", "b", "c"},
+			[]any{"SADD", "s", "d"},
+		},
+		{
+			[]any{"ZADD", "z", float64(1), "a", float64(2), "b"},
+			[]any{"ZADD", "z", float64(3), "c"},
+		},
+	}, bodies)
+}
+
+func TestUnitGetBytesBase64(t *testing.T) {
+	value := []byte{0x0a, 0x03, 0xff, 0x00, 0xfe, 0x80}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "base64", r.Header.Get("Upstash-Encoding"))
+		switch r.URL.Path {
+		case "/get/bin":
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": base64.StdEncoding.EncodeToString(value)})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": nil})
+		}
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableBase64: true})
+	ctx := context.Background()
+
+	got, found, err := u.GetBytes(ctx, "bin")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, value, got)
+
+	got, found, err = u.GetBytes(ctx, "missing")
+	require.NoError(t, err)
+	require.False(t, found)
+	require.Nil(t, got)
+}
+
+func TestUnitSubscribePayloadDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		_, _ = fmt.Fprint(w, "data: subscribe,ch,1\n\n")
+		_, _ = fmt.Fprint(w, "data: plain text\n\n")
+		_, _ = fmt.Fprint(w, "data: \"hello\"\n\n")
+		_, _ = fmt.Fprint(w, "data: \"a\\\"b\"\n\n")
+		_, _ = fmt.Fprint(w, "data: \"not\" json \"\n\n")
+		flusher.Flush()
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs, err := u.Subscribe(ctx, "ch")
+	require.NoError(t, err)
+	require.Equal(t, "plain text", <-msgs)
+	require.Equal(t, "hello", <-msgs)
+	require.Equal(t, `a"b`, <-msgs)
+	require.Equal(t, `"not" json "`, <-msgs)
+}
+
+func TestUnitAuth(t *testing.T) {
+	step := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch step {
+		case 0:
+			require.Equal(t, []any{"AUTH", "app", "secret"}, body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": "OK"})
+		case 1:
+			require.Equal(t, []any{"AUTH", "admin", "wrong"}, body)
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "WRONGPASS invalid username-password pair or user is disabled."})
+		case 2:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, "Unauthorized")
+		}
+		step++
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", Username: "app"})
+	ctx := context.Background()
+
+	require.NoError(t, u.Auth(ctx, "", "secret"))
+
+	err := u.Auth(ctx, "admin", "wrong")
+	require.ErrorIs(t, err, upstash.ErrAuth)
+	require.ErrorContains(t, err, "WRONGPASS")
+
+	_, err = u.Get(ctx, "k")
+	require.ErrorIs(t, err, upstash.ErrAuth)
+}
+
+func TestUnitSelect(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"SELECT", float64(1)},
+				[]any{"GET", "k"},
+			},
+			response: []any{
+				map[string]any{"result": "OK"},
+				map[string]any{"result": "v"},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{method: "POST", expectedBody: []any{"SELECT", float64(2)}, response: "OK", status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	pipe := u.Pipeline()
+	pipe.Select(1)
+	pipe.Push("GET", "k")
+	res, err := pipe.Exec(ctx)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	require.NoError(t, u.Select(ctx, 2))
+}
+
+func TestUnitCountsInt64(t *testing.T) {
+	var bodies [][]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_, _ = fmt.Fprint(w, `{"result":3000000000}`)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", UseNumber: true})
+	ctx := context.Background()
+
+	const want = int64(3_000_000_000)
+	for _, count := range []func() (int64, error){
+		func() (int64, error) { return u.XLenInt64(ctx, "s") },
+		func() (int64, error) { return u.DBSizeInt64(ctx) },
+		func() (int64, error) { return u.SCardInt64(ctx, "set") },
+		func() (int64, error) { return u.ZCardInt64(ctx, "z") },
+		func() (int64, error) { return u.LLenInt64(ctx, "l") },
+	} {
+		n, err := count()
+		require.NoError(t, err)
+		require.Equal(t, want, n)
+	}
+	require.Equal(t, [][]any{
+		{"XLEN", "s"},
+		{"DBSIZE"},
+		{"SCARD", "set"},
+		{"ZCARD", "z"},
+		{"LLEN", "l"},
+	}, bodies)
+}
+
+func TestUnitSetGetOld(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"set", "k", "new", "ex", "10", "get"}, response: "old", status: 200},
+		{method: "POST", expectedBody: []any{"set", "fresh", "v", "nx", "get"}, response: nil, status: 200},
+		// NX skips the SET because the key exists, but GET still returns the current value.
+		{method: "POST", expectedBody: []any{"set", "k", "ignored", "nx", "get"}, response: "new", status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	old, existed, err := u.SetGetOld(ctx, "k", "new", upstash.SetOptions{EX: 10})
+	require.NoError(t, err)
+	require.True(t, existed)
+	require.Equal(t, "old", old)
+
+	old, existed, err = u.SetGetOld(ctx, "fresh", "v", upstash.SetOptions{NX: true})
+	require.NoError(t, err)
+	require.False(t, existed)
+	require.Equal(t, "", old)
+
+	old, existed, err = u.SetGetOld(ctx, "k", "ignored", upstash.SetOptions{NX: true})
+	require.NoError(t, err)
+	require.True(t, existed)
+	require.Equal(t, "new", old)
+}
+
+func TestUnitMGetBatched(t *testing.T) {
+	store := map[string]string{"k1": "v1", "k3": "v3", "k4": "v4"}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/pipeline" {
+			var body [][]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			results := make([]any, len(body))
+			for i, cmd := range body {
+				require.Equal(t, "MGET", cmd[0])
+				values := make([]any, 0, len(cmd)-1)
+				for _, k := range cmd[1:] {
+					if v, ok := store[k.(string)]; ok {
+						values = append(values, v)
+					} else {
+						values = append(values, nil)
+					}
+				}
+				results[i] = map[string]any{"result": values}
+			}
+			_ = json.NewEncoder(w).Encode(results)
+			return
+		}
+		values := []any{}
+		for _, k := range strings.Split(strings.TrimPrefix(r.URL.Path, "/mget/"), "/") {
+			if v, ok := store[k]; ok {
+				values = append(values, v)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": values})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", MaxBatchSize: 2})
+	ctx := context.Background()
+
+	single, err := u.MGet(ctx, []string{"k1", "k2"})
+	require.NoError(t, err)
+
+	vals, err := u.MGet(ctx, []string{"k1", "k2", "k3", "k5", "k4"})
+	require.NoError(t, err)
+	missing := single[1]
+	require.Equal(t, []string{"v1", missing, "v3", missing, "v4"}, vals)
+	require.Equal(t, []string{"/mget/k1/k2", "/pipeline"}, paths)
+}
+
+func TestUnitIdleKeys(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"OBJECT", "IDLETIME", "cold"},
+				[]any{"OBJECT", "IDLETIME", "hot"},
+				[]any{"OBJECT", "IDLETIME", "missing"},
+				[]any{"OBJECT", "IDLETIME", "edge"},
+			},
+			response: []any{
+				map[string]any{"result": float64(7200)},
+				map[string]any{"result": float64(5)},
+				map[string]any{"result": nil},
+				map[string]any{"result": float64(3600)},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{method: "POST", expectedBody: []any{"OBJECT", "IDLETIME", "cold"}, response: float64(90), status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	keys, err := u.IdleKeys(ctx, []string{"cold", "hot", "missing", "edge"}, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cold", "edge"}, keys)
+
+	idle, err := u.ObjectIdleTime(ctx, "cold")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Second, idle)
+}
+
+func TestUnitXInfoGroups(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XINFO", "GROUPS", "s"},
+			response: []any{
+				[]any{
+					"name", "workers",
+					"consumers", float64(2),
+					"pending", float64(3),
+					"last-delivered-id", "5-0",
+					"entries-read", float64(5),
+					"lag", float64(4),
+				},
+				[]any{
+					"name", "trimmed",
+					"consumers", float64(1),
+					"pending", float64(0),
+					"last-delivered-id", "1-0",
+					"entries-read", float64(1),
+					"lag", nil,
+				},
+			},
+			status: 200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"XINFO", "GROUPS", "s"},
+			response: []any{
+				[]any{
+					"name", "legacy",
+					"consumers", float64(1),
+					"pending", float64(2),
+					"last-delivered-id", "2-0",
+				},
+			},
+			status: 200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+	ptr := func(n int64) *int64 { return &n }
+
+	groups, err := u.XInfoGroups(ctx, "s")
+	require.NoError(t, err)
+	require.Equal(t, []upstash.XGroupInfo{
+		{Name: "workers", Consumers: 2, Pending: 3, LastDeliveredID: "5-0", EntriesRead: ptr(5), Lag: ptr(4)},
+		{Name: "trimmed", Consumers: 1, Pending: 0, LastDeliveredID: "1-0", EntriesRead: ptr(1)},
+	}, groups)
+
+	groups, err = u.XInfoGroups(ctx, "s")
+	require.NoError(t, err)
+	require.Equal(t, []upstash.XGroupInfo{
+		{Name: "legacy", Consumers: 1, Pending: 2, LastDeliveredID: "2-0"},
+	}, groups)
+}
+
+func TestUnitHealthCheck(t *testing.T) {
+	var mu sync.Mutex
+	healthy := true
+	pings := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pings++
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "unavailable"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "PONG"})
+	}))
+	defer server.Close()
+
+	ticks := make(chan time.Time)
+	restore := upstash.SetHealthCheckAfter(func(d time.Duration) <-chan time.Time {
+		require.Equal(t, time.Minute, d)
+		return ticks
+	})
+	defer restore()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", HealthCheckInterval: time.Minute, Retry: upstash.RetryConfig{Retries: 1, Backoff: noBackoff}})
+	defer u.StopHealthCheck()
+
+	require.Eventually(t, u.IsHealthy, time.Second, 5*time.Millisecond)
+	for range 10 {
+		require.True(t, u.IsHealthy())
+	}
+
+	mu.Lock()
+	require.Equal(t, 1, pings)
+	healthy = false
+	mu.Unlock()
+
+	// Advance the clock past the interval.
+	ticks <- time.Now()
+	require.Eventually(t, func() bool { return !u.IsHealthy() }, time.Second, 5*time.Millisecond)
+
+	// The failing PING is retried once.
+	mu.Lock()
+	require.Equal(t, 3, pings)
+	mu.Unlock()
+
+	disabled, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+	require.False(t, disabled.IsHealthy())
+}
+
+func TestUnitZScanPairs(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"ZSCAN", "z", "0", "COUNT", float64(2)}, response: []any{"5", []any{"a", "1", "b", "2.5"}}, status: 200},
+		{method: "POST", expectedBody: []any{"ZSCAN", "z", "5", "COUNT", float64(2)}, response: []any{"0", []any{"c", "-inf"}}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	var members []upstash.ZMember
+	cursor := "0"
+	for {
+		page, err := u.ZScanPairs(ctx, "z", cursor, upstash.ScanOptions{Count: 2})
+		require.NoError(t, err)
+		members = append(members, page.Members...)
+		cursor = page.Cursor
+		if cursor == "0" {
+			break
+		}
+	}
+	require.Equal(t, []upstash.ZMember{
+		{Score: 1, Member: "a"},
+		{Score: 2.5, Member: "b"},
+		{Score: math.Inf(-1), Member: "c"},
+	}, members)
+}
+
+func TestUnitUnexpectedResponse(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "GET", path: "/get/k", response: map[string]any{"foo": 1}, rawResponse: true, status: 200},
+	})
+	defer close()
+
+	_, err := u.Get(context.Background(), "k")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, `{"foo":1}`)
+}
+
+func TestUnitGeoAddWithResult(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"GEOPOS", "fleet", "car1", "car2", "car3"},
+				[]any{"GEOADD", "fleet", "CH", float64(13.4), float64(52.5), "car1", float64(2.35), float64(48.85), "car2", float64(-0.12), float64(51.5), "car3"},
+				[]any{"GEOPOS", "fleet", "car1", "car2", "car3"},
+			},
+			response: []any{
+				map[string]any{"result": []any{
+					[]any{"13.39999943971633911", "52.49999940287337669"},
+					[]any{"2.29999786615371704", "48.85000009661699235"},
+					nil,
+				}},
+				map[string]any{"result": float64(2)},
+				map[string]any{"result": []any{
+					[]any{"13.39999943971633911", "52.49999940287337669"},
+					[]any{"2.35000044107437134", "48.85000009661699235"},
+					[]any{"-0.11999756097793579", "51.49999946109708729"},
+				}},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"GEOADD", "fleet", "CH", float64(13.4), float64(52.5), "car1"},
+			response:     float64(0),
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.GeoAddWithResult(ctx, "fleet", upstash.GeoAddOptions{CH: true, TrackChanges: true},
+		upstash.GeoLocation{Longitude: 13.4, Latitude: 52.5, Member: "car1"},
+		upstash.GeoLocation{Longitude: 2.35, Latitude: 48.85, Member: "car2"},
+		upstash.GeoLocation{Longitude: -0.12, Latitude: 51.5, Member: "car3"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, upstash.GeoAddResult{Count: 2, Changed: []string{"car2", "car3"}}, res)
+
+	res, err = u.GeoAddWithResult(ctx, "fleet", upstash.GeoAddOptions{CH: true},
+		upstash.GeoLocation{Longitude: 13.4, Latitude: 52.5, Member: "car1"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, upstash.GeoAddResult{Count: 0}, res)
+}
+
+func TestUnitXAckDelAndXDelEx(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"XACKDEL", "s", "workers", "ACKED", "IDS", float64(3), "1-0", "2-0", "3-0"},
+			response:     []any{float64(1), float64(2), float64(-1)},
+			status:       200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"XDELEX", "s", "IDS", float64(2), "1-0", "9-0"},
+			response:     []any{float64(1), float64(-1)},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	res, err := u.XAckDel(ctx, "s", "workers", upstash.XAckDelOptions{Acked: true}, "1-0", "2-0", "3-0")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, -1}, res)
+
+	res, err = u.XDelEx(ctx, "s", upstash.XAckDelOptions{}, "1-0", "9-0")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, -1}, res)
+}
+
+func TestUnitJsonLenPtr(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"JSON.ARRLEN", "doc", "$..a"}, response: []any{float64(0), nil, float64(3)}, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.OBJLEN", "doc", "$..a"}, response: []any{nil, float64(0)}, status: 200},
+		{method: "POST", expectedBody: []any{"JSON.STRLEN", "doc", "$..a"}, response: []any{float64(5), nil}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+	intPtr := func(n int) *int { return &n }
+
+	arr, err := u.JsonArrLenPtr(ctx, "doc", "$..a")
+	require.NoError(t, err)
+	require.Equal(t, []*int{intPtr(0), nil, intPtr(3)}, arr)
+
+	obj, err := u.JsonObjLenPtr(ctx, "doc", "$..a")
+	require.NoError(t, err)
+	require.Equal(t, []*int{nil, intPtr(0)}, obj)
+
+	str, err := u.JsonStrLenPtr(ctx, "doc", "$..a")
+	require.NoError(t, err)
+	require.Equal(t, []*int{intPtr(5), nil}, str)
+}
+
+func TestUnitLatency(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"LATENCY", "LATEST"},
+			response: []any{
+				[]any{"command", float64(1700000000), float64(12), float64(250)},
+				[]any{"fork", float64(1700000100), float64(3), float64(3)},
+			},
+			status: 200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"LATENCY", "HISTORY", "command"},
+			response: []any{
+				[]any{float64(1699999000), float64(250)},
+				[]any{float64(1700000000), float64(12)},
+			},
+			status: 200,
+		},
+		{method: "POST", expectedBody: []any{"LATENCY", "RESET", "command"}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"LATENCY", "LATEST"}, response: map[string]any{"error": "NOPERM this user has no permissions to run the 'latency' command"}, rawResponse: true, status: 400},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	events, err := u.LatencyLatest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.LatencyEvent{
+		{Name: "command", Timestamp: 1700000000, Latest: 12, Max: 250},
+		{Name: "fork", Timestamp: 1700000100, Latest: 3, Max: 3},
+	}, events)
+
+	samples, err := u.LatencyHistory(ctx, "command")
+	require.NoError(t, err)
+	require.Equal(t, []upstash.LatencySample{
+		{Timestamp: 1699999000, Latency: 250},
+		{Timestamp: 1700000000, Latency: 12},
+	}, samples)
+
+	n, err := u.LatencyReset(ctx, "command")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = u.LatencyLatest(ctx)
+	require.ErrorIs(t, err, upstash.ErrNoPerm)
+}
+
+func TestUnitWaitDetailedClampsToDeadline(t *testing.T) {
+	var sentTimeout float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sentTimeout = body[2].(float64)
+		// Behave like WAIT: reply with the replicas acknowledged so far once the timeout elapses,
+		// plus some network latency that pushes the reply past the caller's deadline.
+		time.Sleep(time.Duration(sentTimeout)*time.Millisecond + 50*time.Millisecond)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": 1})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	res, err := u.WaitDetailed(ctx, 2, 5000)
+	require.NoError(t, err)
+	require.Equal(t, upstash.WaitResult{Acknowledged: 1, TimedOut: true}, res)
+	require.Greater(t, sentTimeout, float64(0))
+	require.LessOrEqual(t, sentTimeout, float64(200))
+	require.Error(t, ctx.Err())
+}
+
+func TestUnitWaitDetailedCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": 1})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t"})
+
+	for _, withDeadline := range []bool{true, false} {
+		parent := context.Background()
+		if withDeadline {
+			var cancelParent context.CancelFunc
+			parent, cancelParent = context.WithTimeout(parent, time.Minute)
+			defer cancelParent()
+		}
+		ctx, cancel := context.WithCancel(parent)
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		res, err := u.WaitDetailed(ctx, 1, 0)
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, res.TimedOut)
+		require.Less(t, time.Since(start), time.Second)
+	}
+}
+
+func TestUnitCounterInt64Variants(t *testing.T) {
+	var bodies [][]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_, _ = fmt.Fprint(w, `{"result":9007199254740993}`)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", UseNumber: true})
+	ctx := context.Background()
+
+	const want = int64(9_007_199_254_740_993)
+	for _, count := range []func() (int64, error){
+		func() (int64, error) { return u.IncrInt64(ctx, "c") },
+		func() (int64, error) { return u.IncrByInt64(ctx, "c", 5_000_000_000) },
+		func() (int64, error) { return u.DecrInt64(ctx, "c") },
+		func() (int64, error) { return u.DecrByInt64(ctx, "c", 5_000_000_000) },
+		func() (int64, error) { return u.StrLenInt64(ctx, "s") },
+		func() (int64, error) { return u.DelInt64(ctx, "a", "b") },
+		func() (int64, error) { return u.ExistsInt64(ctx, "a") },
+	} {
+		n, err := count()
+		require.NoError(t, err)
+		require.Equal(t, want, n)
+	}
+	require.Equal(t, [][]any{
+		{"INCR", "c"},
+		{"INCRBY", "c", float64(5_000_000_000)},
+		{"DECR", "c"},
+		{"DECRBY", "c", float64(5_000_000_000)},
+		{"STRLEN", "s"},
+		{"DEL", "a", "b"},
+		{"EXISTS", "a"},
+	}, bodies)
+}
+
+func TestUnitUnexpectedReplyTypes(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"HLEN", "h"}, response: "not a number", status: 200},
+		{method: "POST", expectedBody: []any{"ZSCORE", "z", "m"}, response: []any{"1"}, status: 200},
+		{method: "POST", expectedBody: []any{"SMEMBERS", "s"}, response: "oops", status: 200},
+		{method: "POST", expectedBody: []any{"WATCH", "k"}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"ZSCORE", "z", "m"}, response: "1.5", status: 200},
+		{method: "POST", expectedBody: []any{"OBJECT", "IDLETIME", "k"}, response: "soon", status: 200},
+		{
+			method:       "POST",
+			path:         "/pipeline",
+			expectedBody: []any{[]any{"RPUSH", "l", "a"}, []any{"LLEN", "l"}},
+			response:     []any{map[string]any{"result": float64(1)}, map[string]any{"result": "one"}},
+			rawResponse:  true,
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	_, err := u.HLen(ctx, "h")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, "unexpected type string for HLEN")
+
+	_, err = u.ZScore(ctx, "z", "m")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, "ZSCORE")
+
+	_, err = u.SMembers(ctx, "s")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+
+	_, err = u.Watch(ctx, "k")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, "unexpected type float64 for WATCH")
+
+	score, err := u.ZScore(ctx, "z", "m")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, score)
+
+	_, err = u.ObjectIdleTime(ctx, "k")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, "OBJECT IDLETIME")
+
+	// Replies read from pipelines are checked too.
+	_, err = u.PushConfirmed(ctx, "l", "a")
+	require.ErrorIs(t, err, upstash.ErrUnexpectedResponse)
+	require.ErrorContains(t, err, "unexpected type string for LLEN")
+}
+
+func TestUnitXGroupConsumers(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"XGROUP", "CREATECONSUMER", "s", "workers", "w3"}, response: float64(1), status: 200},
+		{method: "POST", expectedBody: []any{"XGROUP", "CREATECONSUMER", "s", "workers", "w3"}, response: float64(0), status: 200},
+		{
+			method:       "POST",
+			expectedBody: []any{"XINFO", "CONSUMERS", "s", "workers"},
+			response: []any{
+				[]any{"name", "w1", "pending", float64(2), "idle", float64(1500), "inactive", float64(1200)},
+				[]any{"name", "w3", "pending", float64(0), "idle", float64(10), "inactive", float64(-1)},
+			},
+			status: 200,
+		},
+		{
+			method:       "POST",
+			expectedBody: []any{"XINFO", "CONSUMERS", "s", "workers"},
+			response:     []any{[]any{"name", "w1", "pending", float64(2), "idle", float64(1500)}},
+			status:       200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	created, err := u.XGroupCreateConsumer(ctx, "s", "workers", "w3")
+	require.NoError(t, err)
+	require.Equal(t, 1, created)
+
+	created, err = u.XGroupCreateConsumer(ctx, "s", "workers", "w3")
+	require.NoError(t, err)
+	require.Equal(t, 0, created)
+
+	consumers, err := u.XGroupListConsumers(ctx, "s", "workers")
+	require.NoError(t, err)
+	require.Len(t, consumers, 2)
+	require.Equal(t, "w1", consumers[0].Name)
+	require.Equal(t, int64(2), consumers[0].Pending)
+	require.Equal(t, int64(1500), consumers[0].Idle)
+	require.Equal(t, int64(1200), *consumers[0].Inactive)
+	require.Equal(t, "w3", consumers[1].Name)
+	require.Equal(t, int64(-1), *consumers[1].Inactive)
+
+	// Servers older than Redis 7.2 do not report inactive.
+	consumers, err = u.XGroupListConsumers(ctx, "s", "workers")
+	require.NoError(t, err)
+	require.Len(t, consumers, 1)
+	require.Nil(t, consumers[0].Inactive)
+}
+
+func TestUnitArgEncoder(t *testing.T) {
+	var bodies []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/pipeline" {
+			_, _ = fmt.Fprint(w, `[{"result":"OK"},{"result":1}]`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"result":"OK"}`)
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{
+		Url:   server.URL,
+		Token: "t",
+		ArgEncoder: func(v any) (string, error) {
+			if ts, ok := v.(time.Time); ok {
+				return ts.UTC().Format(time.RFC3339), nil
+			}
+			return fmt.Sprint(v), nil
+		},
+	})
+	ctx := context.Background()
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	_, err := u.Send(ctx, "SET", "last-login", at)
+	require.NoError(t, err)
+
+	p := u.Pipeline()
+	p.Push("HSET", "user:1", "seen", at)
+	p.Push("EXPIRE", "user:1", 60)
+	_, err = p.Exec(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, []any{
+		[]any{"SET", "last-login", "2024-05-01T12:30:00Z"},
+		[]any{
+			[]any{"HSET", "user:1", "seen", "2024-05-01T12:30:00Z"},
+			[]any{"EXPIRE", "user:1", "60"},
+		},
+	}, bodies)
+}
+
+func TestUnitZAddWithArgs(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"ZADD", "z", "XX", "GT", "CH", float64(1), "a", float64(2.5), "b"},
+			response:     float64(2),
+			status:       200,
+		},
+		{method: "POST", expectedBody: []any{"ZADD", "z", "INCR", float64(5), "a"}, response: "6", status: 200},
+		{method: "POST", expectedBody: []any{"ZADD", "z", "NX", "INCR", float64(5), "a"}, response: nil, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	changed, err := u.ZAddWithArgs(ctx, "z", upstash.ZAddArgs{
+		XX: true, GT: true, CH: true,
+		Members: []upstash.ZMember{{Score: 1, Member: "a"}, {Score: 2.5, Member: "b"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, float64(2), changed)
+
+	score, err := u.ZAddWithArgs(ctx, "z", upstash.ZAddArgs{INCR: true, Members: []upstash.ZMember{{Score: 5, Member: "a"}}})
+	require.NoError(t, err)
+	require.Equal(t, float64(6), score)
+
+	_, err = u.ZAddWithArgs(ctx, "z", upstash.ZAddArgs{NX: true, INCR: true, Members: []upstash.ZMember{{Score: 5, Member: "a"}}})
+	require.ErrorIs(t, err, upstash.ErrNil)
+}
+
+func TestUnitZRangeByScoreAndLex(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"ZRANGEBYSCORE", "z", "(5", "+inf", "LIMIT", float64(0), float64(2)}, response: []any{"a", "b"}, status: 200},
+		{method: "POST", expectedBody: []any{"ZRANGEBYSCORE", "z", float64(1), float64(3)}, response: []any{}, status: 200},
+		{method: "POST", expectedBody: []any{"ZRANGEBYLEX", "z", "[a", "(c"}, response: []any{"a", "b"}, status: 200},
+		{method: "POST", expectedBody: []any{"ZRANGEBYLEX", "z", "-", "+", "LIMIT", float64(0), float64(10)}, response: nil, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	members, err := u.ZRangeByScore(ctx, "z", "(5", "+inf", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, members)
+
+	members, err = u.ZRangeByScore(ctx, "z", 1, 3)
+	require.NoError(t, err)
+	require.Empty(t, members)
+
+	members, err = u.ZRangeByLex(ctx, "z", "[a", "(c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, members)
+
+	members, err = u.ZRangeByLex(ctx, "z", "-", "+", 10)
+	require.NoError(t, err)
+	require.Empty(t, members)
+}
+
+func TestUnitDeleteByPattern(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"SCAN", "0", "MATCH", "session:*", "COUNT", float64(2)}, response: []any{"7", []any{"session:1", "session:2"}}, status: 200},
+		{method: "POST", expectedBody: []any{"UNLINK", "session:1", "session:2"}, response: float64(2), status: 200},
+		{method: "POST", expectedBody: []any{"SCAN", "7", "MATCH", "session:*", "COUNT", float64(2)}, response: []any{"9", []any{}}, status: 200},
+		{method: "POST", expectedBody: []any{"SCAN", "9", "MATCH", "session:*", "COUNT", float64(2)}, response: []any{"0", []any{"session:3"}}, status: 200},
+		{method: "POST", expectedBody: []any{"UNLINK", "session:3"}, response: float64(1), status: 200},
+	})
+	defer close()
+
+	var progress []int
+	deleted, err := u.DeleteByPattern(context.Background(), "session:*", 2, func(deletedSoFar int) {
+		progress = append(progress, deletedSoFar)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, deleted)
+	require.Equal(t, []int{2, 3}, progress)
+}
+
+func TestUnitTLSConfig(t *testing.T) {
+	client := upstash.NewHTTPClient(&tls.Config{MinVersion: tls.VersionTLS13})
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+
+	require.Nil(t, upstash.NewHTTPClient(nil).Transport)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"result":"PONG"}`)
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", TLSConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}})
+	res, err := u.Ping(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "PONG", res)
+}
+
+func TestUnitZRangeWithScores(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"ZRANGE", "z", float64(0), float64(-1), "WITHSCORES"}, response: []any{"a", "1.5", "b", "inf"}, status: 200},
+		{method: "POST", expectedBody: []any{"ZRANGE", "z", float64(0), float64(1), "WITHSCORES"}, response: []any{"a", float64(1.5), "b", float64(2)}, status: 200},
+		{method: "POST", expectedBody: []any{"ZRANGE", "z", float64(0), float64(-1), "WITHSCORES"}, response: []any{"a", "abc"}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	members, err := u.ZRangeWithScores(ctx, "z", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.ZMember{{Score: 1.5, Member: "a"}, {Score: math.Inf(1), Member: "b"}}, members)
+
+	members, err = u.ZRangeWithScores(ctx, "z", 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.ZMember{{Score: 1.5, Member: "a"}, {Score: 2, Member: "b"}}, members)
+
+	_, err = u.ZRangeWithScores(ctx, "z", 0, -1)
+	require.ErrorContains(t, err, "invalid score for member a")
+}
+
+func TestUnitZRangeWithScoresBase64(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.StdEncoding.EncodeToString
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": []any{enc([]byte("a")), enc([]byte("3.25"))}})
+	}))
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableBase64: true})
+	members, err := u.ZRangeWithScores(context.Background(), "z", 0, -1)
+	require.NoError(t, err)
+	require.Equal(t, []upstash.ZMember{{Score: 3.25, Member: "a"}}, members)
+}
+
+func TestUnitSetStoreAndGet(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"SINTERSTORE", "dest", "a", "b"},
+				[]any{"SMEMBERS", "dest"},
+			},
+			response: []any{
+				map[string]any{"result": float64(2)},
+				map[string]any{"result": []any{"x", "y"}},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"SUNIONSTORE", "dest", "a", "b"},
+				[]any{"SMEMBERS", "dest"},
+			},
+			response: []any{
+				map[string]any{"result": float64(3)},
+				map[string]any{"result": []any{"x", "y", "z"}},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/pipeline",
+			expectedBody: []any{
+				[]any{"SDIFFSTORE", "dest", "a", "b"},
+				[]any{"SMEMBERS", "dest"},
+			},
+			response: []any{
+				map[string]any{"error": "WRONGTYPE Operation against a key holding the wrong kind of value"},
+				map[string]any{"result": []any{}},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	n, members, err := u.SInterStoreAndGet(ctx, "dest", "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"x", "y"}, members)
+
+	n, members, err = u.SUnionStoreAndGet(ctx, "dest", "a", "b")
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, []string{"x", "y", "z"}, members)
+
+	_, _, err = u.SDiffStoreAndGet(ctx, "dest", "a", "b")
+	require.ErrorIs(t, err, upstash.ErrWrongType)
+}
+
+// newEchoPipelineServer returns a server replying to each command with its first argument,
+// or with an error for the command FAIL, and counting the HTTP requests it receives.
+func newEchoPipelineServer(requests *atomic.Int64, sizes chan<- int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		reply := func(cmd []any) map[string]any {
+			if cmd[0] == "FAIL" {
+				return map[string]any{"error": "ERR failed"}
+			}
+			return map[string]any{"result": cmd[1]}
+		}
+		if r.URL.Path != "/pipeline" {
+			var cmd []any
+			_ = json.NewDecoder(r.Body).Decode(&cmd)
+			_ = json.NewEncoder(w).Encode(reply(cmd))
+			return
+		}
+		var cmds [][]any
+		_ = json.NewDecoder(r.Body).Decode(&cmds)
+		if sizes != nil {
+			sizes <- len(cmds)
+		}
+		results := make([]any, len(cmds))
+		for i, cmd := range cmds {
+			results[i] = reply(cmd)
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+}
+
+func TestUnitAutoPipelining(t *testing.T) {
+	var requests atomic.Int64
+	sizes := make(chan int, 10)
+	server := newEchoPipelineServer(&requests, sizes)
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableAutoPipelining: true, AutoPipelineWindow: 100 * time.Millisecond})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]any, 10)
+	errs := make([]error, 10)
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			command := "GET"
+			if i == 3 {
+				command = "FAIL"
+			}
+			results[i], errs[i] = u.Send(ctx, command, fmt.Sprint("key", i))
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(1), requests.Load())
+	require.Equal(t, 10, <-sizes)
+	for i := range 10 {
+		if i == 3 {
+			require.ErrorContains(t, errs[i], "ERR failed")
+			continue
+		}
+		require.NoError(t, errs[i])
+		require.Equal(t, fmt.Sprint("key", i), results[i])
+	}
+}
+
+func TestUnitAutoPipeliningMaxCommands(t *testing.T) {
+	var requests atomic.Int64
+	sizes := make(chan int, 10)
+	server := newEchoPipelineServer(&requests, sizes)
+	defer server.Close()
+
+	// The window never elapses during the test, so only the cap can flush the queue.
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableAutoPipelining: true, AutoPipelineWindow: time.Hour, AutoPipelineMaxCommands: 3})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]any, 6)
+	errs := make([]error, 6)
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = u.Send(ctx, "GET", fmt.Sprint("key", i))
+		}()
+	}
+	wg.Wait()
+
+	for i := range 6 {
+		require.NoError(t, errs[i])
+		require.Equal(t, fmt.Sprint("key", i), results[i])
+	}
+
+	require.Equal(t, int64(2), requests.Load())
+	require.Equal(t, 3, <-sizes)
+	require.Equal(t, 3, <-sizes)
+}
+
+func TestUnitAutoPipeliningSkipsBlockingCommands(t *testing.T) {
+	var requests atomic.Int64
+	server := newEchoPipelineServer(&requests, nil)
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableAutoPipelining: true, AutoPipelineWindow: time.Hour})
+
+	res, err := u.Send(context.Background(), "BLPOP", "list", 1)
+	require.NoError(t, err)
+	require.Equal(t, "list", res)
+	require.Equal(t, int64(1), requests.Load())
+}
+
+func TestUnitAutoPipeliningSkipsConnectionStateCommands(t *testing.T) {
+	var requests atomic.Int64
+	sizes := make(chan int, 10)
+	server := newEchoPipelineServer(&requests, sizes)
+	defer server.Close()
+
+	u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableAutoPipelining: true, AutoPipelineWindow: 100 * time.Millisecond})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var selectErr, getErr error
+	var value any
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		selectErr = u.Select(ctx, 1)
+	}()
+	go func() {
+		defer wg.Done()
+		value, getErr = u.Send(ctx, "GET", "k")
+	}()
+	wg.Wait()
+
+	require.NoError(t, selectErr)
+	require.NoError(t, getErr)
+	require.Equal(t, "k", value)
+	// SELECT is sent on its own; only GET goes through the auto-pipeline.
+	require.Equal(t, int64(2), requests.Load())
+	require.Equal(t, 1, <-sizes)
+	require.Empty(t, sizes)
+}
+
+func BenchmarkAutoPipelining(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("enabled=%t", enabled), func(b *testing.B) {
+			var requests atomic.Int64
+			server := newEchoPipelineServer(&requests, nil)
+			defer server.Close()
+
+			u, _ := upstash.New(upstash.Options{Url: server.URL, Token: "t", EnableAutoPipelining: enabled, AutoPipelineWindow: time.Millisecond})
+			ctx := context.Background()
+
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := u.Send(ctx, "GET", "k"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(requests.Load())/float64(b.N), "requests/op")
+		})
+	}
+}
+
+func TestUnitCommandSupported(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"COMMAND", "INFO", "get"},
+			response:     []any{[]any{"get", float64(2), []any{"readonly", "fast"}, float64(1), float64(1), float64(1)}},
+			status:       200,
+		},
+		{method: "POST", expectedBody: []any{"COMMAND", "INFO", "nosuchcommand"}, response: []any{nil}, status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	ok, err := u.CommandSupported(ctx, "get")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = u.CommandSupported(ctx, "nosuchcommand")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestUnitZIncrByIf(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"ZADD", "peaks", "GT", "INCR", float64(5), "api"},
+				[]any{"ZSCORE", "peaks", "api"},
+			},
+			// The score returned by ZADD INCR takes precedence over the ZSCORE read.
+			response: []any{
+				map[string]any{"result": "15"},
+				map[string]any{"result": "16"},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"ZADD", "peaks", "GT", "INCR", float64(-3), "api"},
+				[]any{"ZSCORE", "peaks", "api"},
+			},
+			response: []any{
+				map[string]any{"result": nil},
+				map[string]any{"result": "15"},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+		{
+			method: "POST",
+			path:   "/multi-exec",
+			expectedBody: []any{
+				[]any{"ZADD", "peaks", "XX", "INCR", float64(1), "web"},
+				[]any{"ZSCORE", "peaks", "web"},
+			},
+			response: []any{
+				map[string]any{"result": nil},
+				map[string]any{"result": nil},
+			},
+			rawResponse: true,
+			status:      200,
+		},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	score, applied, err := u.ZIncrByIf(ctx, "peaks", 5, "api", upstash.ZAddOptions{GT: true})
+	require.NoError(t, err)
+	require.True(t, applied)
+	require.Equal(t, float64(15), score)
+
+	score, applied, err = u.ZIncrByIf(ctx, "peaks", -3, "api", upstash.ZAddOptions{GT: true})
+	require.NoError(t, err)
+	require.False(t, applied)
+	require.Equal(t, float64(15), score)
+
+	score, applied, err = u.ZIncrByIf(ctx, "peaks", 1, "web", upstash.ZAddOptions{XX: true})
+	require.NoError(t, err)
+	require.False(t, applied)
+	require.Equal(t, float64(0), score)
+}
+
+func TestUnitResponseInspector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Upstash-Region", "eu-west-1")
+		var body []any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body[0] == "FAIL" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "ERR unknown command"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": "PONG"})
+	}))
+	defer server.Close()
+
+	var regions []string
+	u, _ := upstash.New(upstash.Options{
+		Url:   server.URL,
+		Token: "t",
+		ResponseInspector: func(header http.Header) {
+			regions = append(regions, header.Get("Upstash-Region"))
+		},
+	})
+	ctx := context.Background()
+
+	_, err := u.Send(ctx, "PING")
+	require.NoError(t, err)
+
+	_, err = u.Send(ctx, "FAIL")
+	require.Error(t, err)
+
+	require.Equal(t, []string{"eu-west-1", "eu-west-1"}, regions)
+}
+
+func TestUnitSetWithOptionsArgs(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{method: "POST", expectedBody: []any{"set", "k", "v", "keepttl", "xx"}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"set", "k", "v", "exat", "1700000000"}, response: "OK", status: 200},
+		{method: "POST", expectedBody: []any{"set", "k", "v", "pxat", "1700000000000", "nx", "get"}, response: nil, status: 200},
+		{method: "POST", expectedBody: []any{"set", "k", "v2", "keepttl", "get"}, response: "v", status: 200},
+	})
+	defer close()
+
+	ctx := context.Background()
+
+	require.NoError(t, u.SetWithOptions(ctx, "k", "v", upstash.SetOptions{KEEPTTL: true, XX: true}))
+	require.NoError(t, u.SetWithOptions(ctx, "k", "v", upstash.SetOptions{EXAT: 1700000000}))
+	require.NoError(t, u.SetWithOptions(ctx, "k", "v", upstash.SetOptions{PXAT: 1700000000000, NX: true, GET: true}))
+
+	old, existed, err := u.SetGetOld(ctx, "k", "v2", upstash.SetOptions{KEEPTTL: true, GET: true})
+	require.NoError(t, err)
+	require.True(t, existed)
+	require.Equal(t, "v", old)
+
+	// Conflicting expiry options are rejected before anything is sent.
+	for _, options := range []upstash.SetOptions{
+		{EX: 10, KEEPTTL: true},
+		{EX: 10, PX: 100},
+		{EXAT: 1, PXAT: 1},
+	} {
+		err := u.SetWithOptions(ctx, "k", "v", options)
+		require.ErrorContains(t, err, "only one of EX, PX, EXAT, PXAT and KEEPTTL")
+	}
+
+	_, _, err = u.SetAndGetTTL(ctx, "k", "v", upstash.SetOptions{GET: true})
+	require.Error(t, err)
+}
+
+func TestUnitJsonMSet(t *testing.T) {
+	u, close := setupMockServer(t, []mockHandler{
+		{
+			method:       "POST",
+			expectedBody: []any{"JSON.MSET", "user:1", "$", `{"name":"Ada"}`, "user:2", "$.age", float64(36)},
+			response:     "OK",
+			status:       200,
+		},
+	})
+	defer close()
+
+	res, err := u.JsonMSet(context.Background(), []upstash.JsonSetEntry{
+		{Key: "user:1", Path: "$", Value: `{"name":"Ada"}`},
+		{Key: "user:2", Path: "$.age", Value: 36},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "OK", res)
+}